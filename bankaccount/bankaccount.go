@@ -0,0 +1,161 @@
+package bankaccount
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+var ErrUnknownFormat = errors.New("unknown account number format")
+
+var ibanRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// Czech domestic account number: [prefix-]number/bank code
+var domesticRegexp = regexp.MustCompile(`^(?:([0-9]{1,6})-)?([0-9]{1,10})/([0-9]{4})$`)
+
+var prefixWeights = []int{10, 5, 8, 4, 2, 1}
+
+var numberWeights = []int{6, 3, 7, 9, 10, 5, 8, 4, 2, 1}
+
+// Czech account number in its parts, without leading zeros
+type Domestic struct {
+	Prefix   string
+	Number   string
+	BankCode string
+}
+
+func (d Domestic) String() string {
+	if d.Prefix == "" {
+		return d.Number + "/" + d.BankCode
+	}
+
+	return d.Prefix + "-" + d.Number + "/" + d.BankCode
+}
+
+// Validate the prefix and number using the mod-11 weighted checksum
+func (d Domestic) IsValid() bool {
+	return checkMod11(d.Prefix, prefixWeights) && checkMod11(d.Number, numberWeights)
+}
+
+func (d Domestic) ToIBAN() string {
+	bban := d.BankCode + fmt.Sprintf("%06s", d.Prefix) + fmt.Sprintf("%010s", d.Number)
+	check := 98 - mod97(bban+"CZ00")
+	return fmt.Sprintf("CZ%02d%s", check, bban)
+}
+
+func checkMod11(digits string, weights []int) bool {
+	digits = fmt.Sprintf("%0*s", len(weights), digits)
+	if len(digits) > len(weights) {
+		return false
+	}
+
+	sum := 0
+	for i, c := range digits {
+		sum += int(c-'0') * weights[i]
+	}
+
+	return sum%11 == 0
+}
+
+// Compute the mod 97 of the number where letters are replaced by
+// numbers 10-35, as specified by ISO 13616
+func mod97(s string) int {
+	var digits strings.Builder
+	for _, c := range s {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(fmt.Sprint(int(c-'A') + 10))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	return int(new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
+
+func trimZeros(s string) string {
+	return strings.TrimLeft(s, "0")
+}
+
+// Validate the IBAN checksum.  The input must be without spaces and
+// uppercase.
+func IsValidIBAN(iban string) bool {
+	if !ibanRegexp.MatchString(iban) {
+		return false
+	}
+
+	return mod97(iban[4:]+iban[:4]) == 1
+}
+
+// Parse a Czech domestic account number or a Czech IBAN
+func ParseDomestic(number string) (Domestic, bool) {
+	number = strings.ToUpper(strings.Join(strings.Fields(number), ""))
+
+	if strings.HasPrefix(number, "CZ") && len(number) == 24 {
+		return Domestic{
+			Prefix:   trimZeros(number[8:14]),
+			Number:   trimZeros(number[14:24]),
+			BankCode: number[4:8],
+		}, true
+	}
+
+	match := domesticRegexp.FindStringSubmatch(number)
+	if match == nil {
+		return Domestic{}, false
+	}
+
+	return Domestic{
+		Prefix:   trimZeros(match[1]),
+		Number:   trimZeros(match[2]),
+		BankCode: match[3],
+	}, true
+}
+
+// Normalize the account number to a canonical form.  Czech numbers,
+// domestic or IBAN, are converted to `prefix-number/code` without
+// leading zeros, other IBANs are uppercased with spaces removed.
+//
+// If the checksum is invalid, the normalized number is returned
+// together with ErrInvalidChecksum.  If the format is not
+// recognized, the input with whitespace removed is returned together
+// with ErrUnknownFormat.
+func Normalize(number string) (string, error) {
+	compact := strings.ToUpper(strings.Join(strings.Fields(number), ""))
+
+	if ibanRegexp.MatchString(compact) {
+		valid := IsValidIBAN(compact)
+
+		if d, ok := ParseDomestic(compact); ok {
+			if !valid || !d.IsValid() {
+				return d.String(), ErrInvalidChecksum
+			}
+			return d.String(), nil
+		}
+
+		if !valid {
+			return compact, ErrInvalidChecksum
+		}
+		return compact, nil
+	}
+
+	if d, ok := ParseDomestic(compact); ok {
+		if !d.IsValid() {
+			return d.String(), ErrInvalidChecksum
+		}
+		return d.String(), nil
+	}
+
+	return strings.Join(strings.Fields(number), ""), ErrUnknownFormat
+}
+
+// Compare two account numbers in any of the supported formats
+func Equal(a string, b string) bool {
+	normalizedA, _ := Normalize(a)
+	normalizedB, _ := Normalize(b)
+
+	return normalizedA == normalizedB
+}
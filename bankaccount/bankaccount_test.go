@@ -0,0 +1,95 @@
+package bankaccount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize_domestic_with_prefix(t *testing.T) {
+	normalized, err := Normalize("000019-2000145399/0800")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "19-2000145399/0800", normalized)
+}
+
+func TestNormalize_domestic_without_prefix(t *testing.T) {
+	normalized, err := Normalize("2000145399 / 0800")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "2000145399/0800", normalized)
+}
+
+func TestNormalize_czech_iban(t *testing.T) {
+	normalized, err := Normalize("CZ65 0800 0000 1920 0014 5399")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "19-2000145399/0800", normalized)
+}
+
+func TestNormalize_foreign_iban(t *testing.T) {
+	normalized, err := Normalize("de89 3704 0044 0532 0130 00")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "DE89370400440532013000", normalized)
+}
+
+func TestNormalize_invalid_domestic_checksum(t *testing.T) {
+	normalized, err := Normalize("19-2000145398/0800")
+
+	assert.Equal(t, ErrInvalidChecksum, err)
+	assert.Equal(t, "19-2000145398/0800", normalized)
+}
+
+func TestNormalize_invalid_iban_checksum(t *testing.T) {
+	_, err := Normalize("CZ66 0800 0000 1920 0014 5399")
+
+	assert.Equal(t, ErrInvalidChecksum, err)
+}
+
+func TestNormalize_unknown_format(t *testing.T) {
+	_, err := Normalize("card payment")
+
+	assert.Equal(t, ErrUnknownFormat, err)
+}
+
+func TestDomesticToIBAN(t *testing.T) {
+	d, ok := ParseDomestic("19-2000145399/0800")
+
+	assert.True(t, ok)
+	assert.Equal(t, "CZ6508000000192000145399", d.ToIBAN())
+}
+
+func TestEqual_across_formats(t *testing.T) {
+	assert.True(t, Equal("19-2000145399/0800", "CZ6508000000192000145399"))
+	assert.True(t, Equal("19-2000145399/0800", "000019-2000145399/0800"))
+	assert.False(t, Equal("19-2000145399/0800", "2000145399/0800"))
+}
+
+func TestLookupAccount_domestic(t *testing.T) {
+	info, exists := LookupAccount("2000145399/0800")
+
+	assert.True(t, exists)
+	assert.Equal(t, "Česká spořitelna, a.s.", info.Name)
+	assert.Equal(t, "GIBACZPX", info.BIC)
+}
+
+func TestLookupAccount_foreign_iban(t *testing.T) {
+	info, exists := LookupAccount("DE89 3704 0044 0532 0130 00")
+
+	assert.True(t, exists)
+	assert.Equal(t, "Commerzbank AG", info.Name)
+}
+
+func TestLookupAccount_unknown_bank_code(t *testing.T) {
+	_, exists := LookupAccount("19-2000145399/0001")
+
+	assert.False(t, exists)
+}
+
+func TestLookupBIC_with_branch_code(t *testing.T) {
+	info, exists := LookupBIC("FIOBCZPPXXX")
+
+	assert.True(t, exists)
+	assert.Equal(t, "2010", info.Code)
+}
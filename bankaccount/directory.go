@@ -0,0 +1,108 @@
+package bankaccount
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+)
+
+//go:embed bankcodes.csv
+var bankCodesCsv string
+
+type BankInfo struct {
+	// ISO country code
+	Country string
+
+	// National bank code, as present in domestic account numbers
+	// and in the IBAN
+	Code string
+
+	BIC string
+
+	Name string
+}
+
+// Length of the bank identifier following the check digits in IBAN
+var ibanBankCodeLength = map[string]int{
+	"AT": 5,
+	"BE": 3,
+	"CZ": 4,
+	"DE": 8,
+	"LT": 5,
+	"SK": 4,
+}
+
+var bankCodes map[string]BankInfo
+
+var bankBICs map[string]BankInfo
+
+func loadBankCodes() {
+	if bankCodes != nil {
+		return
+	}
+
+	reader := csv.NewReader(strings.NewReader(bankCodesCsv))
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		panic(err)
+	}
+
+	bankCodes = make(map[string]BankInfo)
+	bankBICs = make(map[string]BankInfo)
+
+	for _, record := range records[1:] {
+		info := BankInfo{
+			Country: record[0],
+			Code:    record[1],
+			BIC:     record[2],
+			Name:    record[3],
+		}
+
+		bankCodes[info.Country+info.Code] = info
+		if info.BIC != "" {
+			bankBICs[info.BIC[:8]] = info
+		}
+	}
+}
+
+// Find bank by the country and national bank code, e.g. CZ and 0800
+func LookupBankCode(country string, code string) (BankInfo, bool) {
+	loadBankCodes()
+	info, exists := bankCodes[strings.ToUpper(country)+code]
+	return info, exists
+}
+
+// Find bank by BIC.  Only the first 8 characters (without the branch
+// code) are compared.
+func LookupBIC(bic string) (BankInfo, bool) {
+	loadBankCodes()
+	bic = strings.ToUpper(strings.TrimSpace(bic))
+	if len(bic) < 8 {
+		return BankInfo{}, false
+	}
+
+	info, exists := bankBICs[bic[:8]]
+	return info, exists
+}
+
+// Find the bank of an account number in any format supported by
+// Normalize
+func LookupAccount(number string) (BankInfo, bool) {
+	normalized, err := Normalize(number)
+	if err == ErrUnknownFormat {
+		return BankInfo{}, false
+	}
+
+	if d, ok := ParseDomestic(normalized); ok {
+		return LookupBankCode("CZ", d.BankCode)
+	}
+
+	country := normalized[:2]
+	length, exists := ibanBankCodeLength[country]
+	if !exists || len(normalized) < 4+length {
+		return BankInfo{}, false
+	}
+
+	return LookupBankCode(country, normalized[4:4+length])
+}
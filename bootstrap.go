@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bank-to-ledger/journal"
+	"bank-to-ledger/suggest"
+	"fmt"
+	"os"
+	"sort"
+	s "strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type InitFromJournalCommand struct {
+	Args struct {
+		Journals []string `positional-arg-name:"journal" description:"Ledger journal to read"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Node of the accounts hierarchy being built
+type accountNode struct {
+	payees   []string
+	children map[string]*accountNode
+}
+
+func newAccountNode() *accountNode {
+	return &accountNode{children: make(map[string]*accountNode)}
+}
+
+func (n *accountNode) add(account string, payee string) {
+	node := n
+	for _, part := range s.Split(account, ":") {
+		child, exists := node.children[part]
+		if !exists {
+			child = newAccountNode()
+			node.children[part] = child
+		}
+		node = child
+	}
+	node.payees = append(node.payees, payee)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// Payees of an account as a single string or a flow sequence, the
+// forms MapPayees understands
+func payeesNode(payees []string) *yaml.Node {
+	if len(payees) == 1 {
+		return scalarNode(payees[0])
+	}
+
+	node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, payee := range payees {
+		node.Content = append(node.Content, scalarNode(payee))
+	}
+
+	return node
+}
+
+// Accounts hierarchy node.  Payees of accounts which also have
+// subaccounts are listed under `self`.
+func (n *accountNode) toYaml() *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	if len(n.payees) > 0 {
+		node.Content = append(node.Content, scalarNode("self"), payeesNode(n.payees))
+	}
+
+	var names []string
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := n.children[name]
+		value := child.toYaml()
+		if len(child.children) == 0 {
+			value = payeesNode(child.payees)
+		}
+		node.Content = append(node.Content, scalarNode(name), value)
+	}
+
+	return node
+}
+
+// Starter config with the payees and accounts inferred from the
+// journal transactions
+func configFromJournal(transactions []journal.Transaction) ([]byte, error) {
+	accounts := newAccountNode()
+	payees := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, payee := range suggest.InferPayees(transactions) {
+		accounts.add(payee.Account, payee.Name)
+
+		patterns := suggest.InferPatterns(payee.Raw)
+		if len(patterns) == 0 {
+			// MapPayees creates the exact payee name pattern
+			continue
+		}
+		if len(patterns) == 1 && patterns[0] == exactPattern(payee.Name) {
+			continue
+		}
+
+		value := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, pattern := range patterns {
+			value.Content = append(value.Content, &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Tag:   "!!str",
+				Style: yaml.SingleQuotedStyle,
+				Value: pattern,
+			})
+		}
+		if len(patterns) == 1 {
+			value = value.Content[0]
+		}
+		payees.Content = append(payees.Content, scalarNode(payee.Name), value)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(payees.Content) > 0 {
+		root.Content = append(root.Content, scalarNode("payees"), payees)
+	}
+	root.Content = append(root.Content, scalarNode("accounts"), accounts.toYaml())
+
+	return yaml.Marshal(root)
+}
+
+func (c *InitFromJournalCommand) Execute(args []string) error {
+	var transactions []journal.Transaction
+	for _, fileName := range c.Args.Journals {
+		parsed, err := journal.ParseFile(fileName)
+		if err != nil {
+			return err
+		}
+		transactions = append(transactions, parsed...)
+	}
+
+	content, err := configFromJournal(transactions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "# Generated from %s\n", s.Join(c.Args.Journals, ", "))
+	_, err = os.Stdout.Write(content)
+	return err
+}
@@ -0,0 +1,100 @@
+package charset
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Decoding of the single-byte encodings Czech and Slovak banks still
+// export in.  The lower half is ASCII in both.
+
+const (
+	UTF8        = "utf-8"
+	Windows1250 = "windows-1250"
+	ISO88592    = "iso-8859-2"
+)
+
+// windows-1250, bytes 0x80-0xff.  Undefined bytes map to U+FFFD.
+var windows1250 = [128]rune{
+	0x20ac, 0xfffd, 0x201a, 0xfffd, 0x201e, 0x2026, 0x2020, 0x2021, 0xfffd, 0x2030, 0x0160, 0x2039, 0x015a, 0x0164, 0x017d, 0x0179,
+	0xfffd, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014, 0xfffd, 0x2122, 0x0161, 0x203a, 0x015b, 0x0165, 0x017e, 0x017a,
+	0x00a0, 0x02c7, 0x02d8, 0x0141, 0x00a4, 0x0104, 0x00a6, 0x00a7, 0x00a8, 0x00a9, 0x015e, 0x00ab, 0x00ac, 0x00ad, 0x00ae, 0x017b,
+	0x00b0, 0x00b1, 0x02db, 0x0142, 0x00b4, 0x00b5, 0x00b6, 0x00b7, 0x00b8, 0x0105, 0x015f, 0x00bb, 0x013d, 0x02dd, 0x013e, 0x017c,
+	0x0154, 0x00c1, 0x00c2, 0x0102, 0x00c4, 0x0139, 0x0106, 0x00c7, 0x010c, 0x00c9, 0x0118, 0x00cb, 0x011a, 0x00cd, 0x00ce, 0x010e,
+	0x0110, 0x0143, 0x0147, 0x00d3, 0x00d4, 0x0150, 0x00d6, 0x00d7, 0x0158, 0x016e, 0x00da, 0x0170, 0x00dc, 0x00dd, 0x0162, 0x00df,
+	0x0155, 0x00e1, 0x00e2, 0x0103, 0x00e4, 0x013a, 0x0107, 0x00e7, 0x010d, 0x00e9, 0x0119, 0x00eb, 0x011b, 0x00ed, 0x00ee, 0x010f,
+	0x0111, 0x0144, 0x0148, 0x00f3, 0x00f4, 0x0151, 0x00f6, 0x00f7, 0x0159, 0x016f, 0x00fa, 0x0171, 0x00fc, 0x00fd, 0x0163, 0x02d9,
+}
+
+// iso-8859-2, bytes 0x80-0xff.  0x80-0x9f are C1 control characters,
+// 0xc0-0xff are the same as in windows-1250.
+var iso88592 = func() [128]rune {
+	var table [128]rune
+	for i := 0; i < 0x20; i++ {
+		table[i] = rune(0x80 + i)
+	}
+
+	upper := [32]rune{
+		0x00a0, 0x0104, 0x02d8, 0x0141, 0x00a4, 0x013d, 0x015a, 0x00a7, 0x00a8, 0x0160, 0x015e, 0x0164, 0x0179, 0x00ad, 0x017d, 0x017b,
+		0x00b0, 0x0105, 0x02db, 0x0142, 0x00b4, 0x013e, 0x015b, 0x02c7, 0x00b8, 0x0161, 0x015f, 0x0165, 0x017a, 0x02dd, 0x017e, 0x017c,
+	}
+	copy(table[0x20:0x40], upper[:])
+	copy(table[0x40:], windows1250[0x40:])
+
+	return table
+}()
+
+// Guess the encoding of the content.  Valid UTF-8 is assumed to be
+// UTF-8.  Otherwise windows-1250 is recognized by the bytes 0x80-0x9f
+// (e.g. š, ž, ť), which are unused control characters in iso-8859-2,
+// and iso-8859-2 by its codes of Š, Ž, š, ť and ž.  Without either,
+// the more common windows-1250 is assumed; the Czech letters are the
+// same in both then.
+func Detect(content []byte) string {
+	if utf8.Valid(content) {
+		return UTF8
+	}
+
+	iso := false
+	for _, b := range content {
+		if b >= 0x80 && b < 0xa0 {
+			return Windows1250
+		}
+		switch b {
+		case 0xa9, 0xae, 0xb9, 0xbb, 0xbe:
+			iso = true
+		}
+	}
+
+	if iso {
+		return ISO88592
+	}
+
+	return Windows1250
+}
+
+// Decode the content in the given encoding to UTF-8.  Unknown
+// encodings are returned unchanged.
+func ToUTF8(content []byte, encoding string) []byte {
+	var table *[128]rune
+	switch strings.ToLower(encoding) {
+	case Windows1250, "cp1250":
+		table = &windows1250
+	case ISO88592, "latin2":
+		table = &iso88592
+	default:
+		return content
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(content))
+	for _, b := range content {
+		if b < 0x80 {
+			builder.WriteByte(b)
+		} else {
+			builder.WriteRune(table[b-0x80])
+		}
+	}
+
+	return []byte(builder.String())
+}
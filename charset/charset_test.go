@@ -0,0 +1,27 @@
+package charset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, UTF8, Detect([]byte("Příliš žluťoučký kůň")))
+	// "Příliš" in windows-1250
+	assert.Equal(t, Windows1250, Detect([]byte{'P', 0xf8, 0xed, 'l', 'i', 0x9a}))
+	// "Příliš" in iso-8859-2
+	assert.Equal(t, ISO88592, Detect([]byte{'P', 0xf8, 0xed, 'l', 'i', 0xb9}))
+}
+
+func TestToUTF8(t *testing.T) {
+	assert.Equal(t, "Příliš žluťoučký", string(ToUTF8(
+		[]byte{'P', 0xf8, 0xed, 'l', 'i', 0x9a, ' ', 0x9e, 'l', 'u', 0x9d, 'o', 'u', 0xe8, 'k', 0xfd},
+		Windows1250,
+	)))
+	assert.Equal(t, "Příliš žluťoučký", string(ToUTF8(
+		[]byte{'P', 0xf8, 0xed, 'l', 'i', 0xb9, ' ', 0xbe, 'l', 'u', 0xbb, 'o', 'u', 0xe8, 'k', 0xfd},
+		ISO88592,
+	)))
+	assert.Equal(t, "abc", string(ToUTF8([]byte("abc"), UTF8)))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	"bank-to-ledger/detect"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+type AddPayeeCommand struct {
+	Pattern []string `long:"pattern" description:"PayeeRaw pattern of the payee, can be repeated.  Defaults to the exact payee name"`
+
+	Account string `long:"account" description:"Account the payee is placed under in the accounts hierarchy"`
+
+	Args struct {
+		Name string `positional-arg-name:"name" description:"Payee name"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *AddPayeeCommand) Execute(args []string) error {
+	editor, err := cfg.OpenConfigEditor(options.Config)
+	if err != nil {
+		return err
+	}
+
+	patterns := c.Pattern
+	if len(patterns) == 0 {
+		patterns = []string{exactPattern(c.Args.Name)}
+	}
+
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern `%s': %s", pattern, err)
+		}
+	}
+
+	if err := editor.AddPayee(c.Args.Name, patterns, c.Account); err != nil {
+		return err
+	}
+
+	return editor.Save()
+}
+
+type AddPatternCommand struct {
+	Args struct {
+		Name    string `positional-arg-name:"name" description:"Payee name"`
+		Pattern string `positional-arg-name:"pattern" description:"PayeeRaw pattern"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *AddPatternCommand) Execute(args []string) error {
+	editor, err := cfg.OpenConfigEditor(options.Config)
+	if err != nil {
+		return err
+	}
+
+	if _, err := regexp.Compile(c.Args.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern `%s': %s", c.Args.Pattern, err)
+	}
+
+	if err := editor.AddPattern(c.Args.Name, c.Args.Pattern); err != nil {
+		return err
+	}
+
+	return editor.Save()
+}
+
+type DetectBankCommand struct {
+	Name string `long:"name" description:"Name of the bank in the proposed config" default:"mybank"`
+
+	Confirm string `long:"confirm" description:"YAML file with a list of confirmed rows (row, date, payee, amount, currency, note) to choose the columns by and score the guess against"`
+
+	Args struct {
+		File string `positional-arg-name:"csv" description:"Sample csv export of the bank"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *DetectBankCommand) Execute(args []string) error {
+	content, err := os.ReadFile(c.Args.File)
+	if err != nil {
+		return err
+	}
+
+	guess := detect.Detect(content)
+
+	if c.Confirm != "" {
+		confirmations, err := detect.LoadConfirmations(c.Confirm)
+		if err != nil {
+			return err
+		}
+		guess.Apply(confirmations)
+
+		checks := guess.Score(confirmations)
+		passed := 0
+		for _, check := range checks {
+			if check.OK {
+				passed++
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Row %d: %s is `%s', expected `%s'\n", check.Row, check.Field, check.Actual, check.Expected)
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d confirmed values match\n", passed, len(checks))
+	}
+
+	proposal, err := guess.Yaml(c.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(proposal)
+	return err
+}
+
+type PresetsCommand struct {
+	Args struct {
+		Name string `positional-arg-name:"preset" description:"Show the definition of this preset"`
+	} `positional-args:"yes"`
+}
+
+func (c *PresetsCommand) Execute(args []string) error {
+	if c.Args.Name == "" {
+		for _, name := range cfg.PresetNames() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	content, exists := cfg.PresetYaml(c.Args.Name)
+	if !exists {
+		return fmt.Errorf("unknown preset `%s'", c.Args.Name)
+	}
+
+	_, err := os.Stdout.Write(content)
+	return err
+}
+
+type SchemaCommand struct{}
+
+func (c *SchemaCommand) Execute(args []string) error {
+	schema, err := cfg.ConfigSchemaJson()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(string(schema))
+	return err
+}
+
+func init() {
+	parser.AddCommand(
+		"add-payee",
+		"Add a payee to the config",
+		"Add a payee to the config file, keeping its comments and formatting.",
+		&AddPayeeCommand{},
+	)
+
+	parser.AddCommand(
+		"add-pattern",
+		"Add a PayeeRaw pattern to an existing payee",
+		"Add a PayeeRaw pattern to an existing payee in the config file, keeping its comments and formatting.",
+		&AddPatternCommand{},
+	)
+
+	parser.AddCommand(
+		"init-from-journal",
+		"Generate a starter config from a ledger journal",
+		"Infer the payees, their accounts and PayeeRaw patterns from a ledger journal and print a starter config.",
+		&InitFromJournalCommand{},
+	)
+
+	parser.AddCommand(
+		"detect-bank",
+		"Propose a bank config for a csv export",
+		"Inspect a sample csv export and propose a bank config: delimiter, encoding, header row, date layout and the columns.",
+		&DetectBankCommand{},
+	)
+
+	parser.AddCommand(
+		"presets",
+		"List the built-in bank presets",
+		"List the built-in bank presets, or show the definition of one.  Banks in the config can be based on a preset with `preset: <name>`.",
+		&PresetsCommand{},
+	)
+
+	parser.AddCommand(
+		"explain",
+		"Explain how transactions are categorized",
+		"Show the payee, the matched pattern and the account of each transaction, with the config file and line they are defined at.",
+		&ExplainCommand{},
+	)
+
+	parser.AddCommand(
+		"lint",
+		"Check the config for problems",
+		"Report invalid and overlapping patterns, undefined payees, invalid templates, matchers matching every transaction, payees assigned to more than one account and currency mappings without a symbol, with the config file and line.  Unused currency mappings are only reported when bank csv exports are given, as the currencies are known only from the transactions.  Exits with non-zero status if any problem is found.",
+		&LintCommand{},
+	)
+
+	parser.AddCommand(
+		"test-config",
+		"Run the tests defined in the config",
+		"Run the transactions from the `tests` section of the config through payee matching, account resolution, ignoring and twin detection and compare the results with the expectations.  Exits with non-zero status if any test fails.",
+		&TestConfigCommand{},
+	)
+
+	parser.AddCommand(
+		"schema",
+		"Print the JSON Schema of the config",
+		"Print the JSON Schema of the config file, for completion and validation in editors.",
+		&SchemaCommand{},
+	)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"sort"
 )
 
 type Account map[string]interface{}
@@ -73,3 +74,36 @@ func MapPayees(account Account, path string, payees map[string]*Payee) {
 		}
 	}
 }
+
+// List all account names in the accounts hierarchy, including the
+// intermediate accounts.  The names are sorted.
+func ListAccounts(account Account) []string {
+	seen := make(map[string]bool)
+	listAccounts(account, "", seen)
+
+	accounts := make([]string, 0, len(seen))
+	for name := range seen {
+		accounts = append(accounts, name)
+	}
+	sort.Strings(accounts)
+
+	return accounts
+}
+
+func listAccounts(account Account, path string, seen map[string]bool) {
+	for key, value := range account {
+		if key == "self" {
+			continue
+		}
+
+		accountName := key
+		if path != "" {
+			accountName = path + ":" + key
+		}
+
+		seen[accountName] = true
+		if subAccount, ok := value.(Account); ok {
+			listAccounts(subAccount, accountName, seen)
+		}
+	}
+}
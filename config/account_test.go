@@ -147,3 +147,32 @@ accounts:
 	assert.Equal(t, "Expenses:Restaurant", config.Payees["Old Mill"].Account)
 	assert.Equal(t, "Expenses:Restaurant", config.Payees["Qerko"].Account)
 }
+
+func TestListAccounts(t *testing.T) {
+	yamlData := `
+accounts:
+  Expenses:
+    Healthcare:
+      self: Pharmacy
+      Dentist: Dentist
+    Restaurant:
+      - Old Mill
+    Hotel:
+      Airbnb:
+`
+
+	var config Config
+	err := yaml.Unmarshal([]byte(yamlData), &config)
+	if err != nil {
+		t.Fatalf("Error unmarshalling YAML: %v", err)
+	}
+
+	assert.Equal(t, []string{
+		"Expenses",
+		"Expenses:Healthcare",
+		"Expenses:Healthcare:Dentist",
+		"Expenses:Hotel",
+		"Expenses:Hotel:Airbnb",
+		"Expenses:Restaurant",
+	}, ListAccounts(config.Accounts))
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 type ColumnIndices struct {
@@ -22,24 +26,46 @@ type ColumnIndices struct {
 	ReceiverBankCode int `yaml:"receiverBankCode"`
 	NoteForMe        int `yaml:"noteForMe"`
 	NoteForReceiver  int `yaml:"noteForReceiver"`
+	// Unsigned amounts in separate debit (outgoing) and credit
+	// (incoming) columns.  Used when AmountAccount is not present.
+	AmountDebit  int `yaml:"amountDebit"`
+	AmountCredit int `yaml:"amountCredit"`
+	// Column indicating the direction of the payment, see
+	// Bank.DirectionValues
+	Direction int `yaml:"direction"`
+	// Czech payment symbols
+	VariableSymbol int `yaml:"variableSymbol"`
+	ConstantSymbol int `yaml:"constantSymbol"`
+	SpecificSymbol int `yaml:"specificSymbol"`
 }
 
+// Header name of a column.  It can be a single name or a list of
+// aliases (e.g. when the bank renamed the column in a newer export).
+// Names are matched case- and whitespace-insensitively.
+type ColumnName []string
+
 type ColumnNames struct {
-	DateRaw               string `yaml:"dateRaw"`
-	PayeeRaw              string `yaml:"payeeRaw"`
-	CurrencyRaw           string `yaml:"currencyRaw"`
-	CurrencyAccount       string `yaml:"currencyAccount"`
-	PaymentType           string `yaml:"paymentType"`
-	Commodity             string `yaml:"commodity"`
-	CommodityPrice        string `yaml:"commodityPrice"`
-	CommodityQuantity     string `yaml:"commodityQuantity"`
-	AmountReal            string `yaml:"amountReal"`
-	AmountAccount         string `yaml:"amountAccount"`
-	Fee                   string `yaml:"fee"`
-	ReceiverAccountNumber string `yaml:"receiverAccountNumber"`
-	ReceiverBankCode      string `yaml:"receiverBankCode"`
-	NoteForMe             string `yaml:"noteForMe"`
-	NoteForReceiver       string `yaml:"noteForReceiver"`
+	DateRaw               ColumnName `yaml:"dateRaw"`
+	PayeeRaw              ColumnName `yaml:"payeeRaw"`
+	CurrencyRaw           ColumnName `yaml:"currencyRaw"`
+	CurrencyAccount       ColumnName `yaml:"currencyAccount"`
+	PaymentType           ColumnName `yaml:"paymentType"`
+	Commodity             ColumnName `yaml:"commodity"`
+	CommodityPrice        ColumnName `yaml:"commodityPrice"`
+	CommodityQuantity     ColumnName `yaml:"commodityQuantity"`
+	AmountReal            ColumnName `yaml:"amountReal"`
+	AmountAccount         ColumnName `yaml:"amountAccount"`
+	AmountDebit           ColumnName `yaml:"amountDebit"`
+	AmountCredit          ColumnName `yaml:"amountCredit"`
+	Direction             ColumnName `yaml:"direction"`
+	Fee                   ColumnName `yaml:"fee"`
+	ReceiverAccountNumber ColumnName `yaml:"receiverAccountNumber"`
+	ReceiverBankCode      ColumnName `yaml:"receiverBankCode"`
+	NoteForMe             ColumnName `yaml:"noteForMe"`
+	NoteForReceiver       ColumnName `yaml:"noteForReceiver"`
+	VariableSymbol        ColumnName `yaml:"variableSymbol"`
+	ConstantSymbol        ColumnName `yaml:"constantSymbol"`
+	SpecificSymbol        ColumnName `yaml:"specificSymbol"`
 }
 
 // Matchers are a key-value pattern for matching a transaction.  Each field must
@@ -57,6 +83,15 @@ type Matcher struct {
 	ReceiverAccountNumber string `yaml:"receiverAccountNumber"`
 	NoteForMe             string `yaml:"noteForMe"`
 	NoteForReceiver       string `yaml:"noteForReceiver"`
+	VariableSymbol        string `yaml:"variableSymbol"`
+	ConstantSymbol        string `yaml:"constantSymbol"`
+	SpecificSymbol        string `yaml:"specificSymbol"`
+
+	// Group the payee belongs to, see Group
+	Group string `yaml:"group"`
+
+	// Custom fields, see Bank.CustomColumns
+	Custom map[string]string `yaml:"custom"`
 }
 
 type TwinTransaction struct {
@@ -65,7 +100,7 @@ type TwinTransaction struct {
 	//   (produce 2 line transaction only)
 	// - `merge` for adding the account and amount to previous
 	//   transaction (produces transaction with multiple lines)
-	Type string `yaml:"type"`
+	Type string `yaml:"type" enum:"sum,merge"`
 
 	Inverted bool `yaml:"inverted"`
 
@@ -86,6 +121,26 @@ type Bank struct {
 	// Name of the bank (config key)
 	Name string
 
+	// Name of the built-in preset this bank is based on, see
+	// PresetNames.  The settings of the bank are merged on top of
+	// the preset.
+	Preset string `yaml:"preset"`
+
+	// Name of another bank in the config this bank is based on.  The
+	// settings of this bank are merged on top of the other bank's:
+	// mappings are merged recursively, lists are replaced or, for
+	// the lists of rules, appended according to ListMerge.
+	Extends string `yaml:"extends"`
+
+	// How twinTransactions, ignoredTransactions and derivedFields are
+	// merged with the extended bank, `replace` (default) or `append`.
+	// Other lists are always replaced.
+	ListMerge string `yaml:"listMerge" enum:"replace,append"`
+
+	// Abstract banks are only used to be extended by other banks,
+	// they are not used for conversion.  Not inherited.
+	Abstract bool `yaml:"abstract"`
+
 	// Display name of the bank.  Defaults to PayeeName and then Name.
 	DisplayName string `yaml:"displayName"`
 
@@ -118,6 +173,26 @@ type Bank struct {
 
 	FileNamePattern string `yaml:"fileNamePattern"`
 
+	// Field delimiter of the csv export, a single character, e.g.
+	// "\t" or `|`.  If empty, `,` or `;` is used, whichever parses.
+	Delimiter string `yaml:"delimiter"`
+
+	// Number of rows preceding the header row.  Many exports start
+	// with a few lines of account information.
+	SkipRows int `yaml:"skipRows"`
+
+	// Number of rows at the end of the file which are not
+	// transactions (totals, balances...)
+	SkipFooterRows int `yaml:"skipFooterRows"`
+
+	// If set, search this many rows (starting after SkipRows) for a
+	// row matching IdentifyingColumns and use it as the header.
+	HeaderSearchRows int `yaml:"headerSearchRows"`
+
+	// Key-value pairs parsed from the rows preceding the header.
+	// Filled when the csv file is read.
+	Preamble map[string]string `yaml:"-"`
+
 	ColumnNames ColumnNames `yaml:"columnNames"`
 
 	ColumnIndices ColumnIndices `yaml:"columnIndices"`
@@ -125,111 +200,292 @@ type Bank struct {
 	TwinTransactions []TwinTransaction `yaml:"twinTransactions"`
 
 	IgnoredTransactions []IgnoredTransactions `yaml:"ignoredTransactions"`
+
+	// Map values of the Direction column to `debit` or `credit`.
+	// Values not listed here are looked up in defaultDirectionValues.
+	DirectionValues map[string]string `yaml:"directionValues" enum:"debit,credit"`
+
+	// Fields extracted from columns using regular expressions.  They
+	// are applied in order after the columns are read, so they can
+	// override the values read from ColumnIndices.
+	DerivedFields []DerivedField `yaml:"derivedFields"`
+
+	// Map of custom field name to the column it is read from.  Custom
+	// fields are available in matchers, payee patterns and templates
+	// as .Transaction.Custom.<name>
+	CustomColumns map[string]ColumnName `yaml:"customColumns"`
+
+	// Map of custom field name to column index.  Resolved from
+	// CustomColumns when the header is read.
+	CustomColumnIndices map[string]int `yaml:"customColumnIndices"`
+
+	// Meta templates added to every transaction from this bank.
+	// Values which render to an empty string are omitted.
+	Meta *map[string]string `yaml:"meta"`
+
+	// Where the bank is defined
+	Source Source `yaml:"-"`
 }
 
-func (b Bank) NamesToIndices(header []string) ColumnIndices {
-	if (b.ColumnIndices != ColumnIndices{}) {
-		return b.ColumnIndices
-	}
-
-	indices := ColumnIndices{
-		DateRaw:               -1,
-		PayeeRaw:              -1,
-		CurrencyRaw:           -1,
-		CurrencyAccount:       -1,
-		PaymentType:           -1,
-		Commodity:             -1,
-		CommodityPrice:        -1,
-		CommodityQuantity:     -1,
-		AmountReal:            -1,
-		AmountAccount:         -1,
-		Fee:                   -1,
-		ReceiverAccountNumber: -1,
-		ReceiverBankCode:      -1,
-		NoteForMe:             -1,
-		NoteForReceiver:       -1,
-	}
-
-	for i, v := range header {
-		if v == b.ColumnNames.DateRaw {
-			indices.DateRaw = i
-		}
-		if v == b.ColumnNames.PayeeRaw {
-			indices.PayeeRaw = i
-		}
-		if v == b.ColumnNames.CurrencyRaw {
-			indices.CurrencyRaw = i
-		}
-		if v == b.ColumnNames.CurrencyAccount {
-			indices.CurrencyAccount = i
-		}
-		if v == b.ColumnNames.PaymentType {
-			indices.PaymentType = i
-		}
-		if v == b.ColumnNames.Commodity {
-			indices.Commodity = i
-		}
-		if v == b.ColumnNames.CommodityPrice {
-			indices.CommodityPrice = i
-		}
-		if v == b.ColumnNames.CommodityQuantity {
-			indices.CommodityQuantity = i
-		}
-		if v == b.ColumnNames.AmountReal {
-			indices.AmountReal = i
+const (
+	DirectionDebit  = "debit"
+	DirectionCredit = "credit"
+)
+
+var defaultDirectionValues = map[string]string{
+	"d":      DirectionDebit,
+	"dr":     DirectionDebit,
+	"debit":  DirectionDebit,
+	"-":      DirectionDebit,
+	"c":      DirectionCredit,
+	"cr":     DirectionCredit,
+	"credit": DirectionCredit,
+	"+":      DirectionCredit,
+}
+
+// Translate a value of the Direction column to DirectionDebit or
+// DirectionCredit.  Returns empty string if the value is not known.
+func (b Bank) GetDirection(value string) string {
+	value = strings.TrimSpace(value)
+
+	if direction, exists := b.DirectionValues[value]; exists {
+		return direction
+	}
+
+	return defaultDirectionValues[strings.ToLower(value)]
+}
+
+// Normalize column name for comparison: ignore case, surrounding and
+// repeated whitespace and the UTF-8 byte order mark some exports
+// start with.
+func NormalizeColumnName(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Return the index of the first alias found in the header, or -1.
+// If the column is configured but not found, it is recorded as
+// missing.
+func findColumn(header []string, name ColumnName, field string, missing *[]string) int {
+	if len(name) == 0 {
+		return -1
+	}
+
+	for _, alias := range name {
+		normalized := NormalizeColumnName(alias)
+		for i, v := range header {
+			if NormalizeColumnName(v) == normalized {
+				return i
+			}
 		}
-		if v == b.ColumnNames.AmountAccount {
-			indices.AmountAccount = i
+	}
+
+	*missing = append(*missing, fmt.Sprintf("%s (%s)", field, strings.Join(name, " | ")))
+	return -1
+}
+
+func (b Bank) NamesToIndices(header []string) (ColumnIndices, error) {
+	if (b.ColumnIndices != ColumnIndices{}) {
+		return b.ColumnIndices, nil
+	}
+
+	cn := b.ColumnNames
+	var notConfigured []string
+	if len(cn.DateRaw) == 0 {
+		notConfigured = append(notConfigured, "dateRaw")
+	}
+	if len(cn.PayeeRaw) == 0 {
+		notConfigured = append(notConfigured, "payeeRaw")
+	}
+	if len(cn.AmountAccount) == 0 && len(cn.AmountDebit) == 0 && len(cn.AmountCredit) == 0 {
+		notConfigured = append(notConfigured, "amountAccount")
+	}
+	if len(notConfigured) > 0 {
+		return ColumnIndices{}, fmt.Errorf("required columns are not configured in columnNames: %s", strings.Join(notConfigured, ", "))
+	}
+
+	var missing []string
+	indices := NewColumnIndices()
+
+	indices.DateRaw = findColumn(header, cn.DateRaw, "dateRaw", &missing)
+	indices.PayeeRaw = findColumn(header, cn.PayeeRaw, "payeeRaw", &missing)
+	indices.CurrencyRaw = findColumn(header, cn.CurrencyRaw, "currencyRaw", &missing)
+	indices.CurrencyAccount = findColumn(header, cn.CurrencyAccount, "currencyAccount", &missing)
+	indices.PaymentType = findColumn(header, cn.PaymentType, "paymentType", &missing)
+	indices.Commodity = findColumn(header, cn.Commodity, "commodity", &missing)
+	indices.CommodityPrice = findColumn(header, cn.CommodityPrice, "commodityPrice", &missing)
+	indices.CommodityQuantity = findColumn(header, cn.CommodityQuantity, "commodityQuantity", &missing)
+	indices.AmountReal = findColumn(header, cn.AmountReal, "amountReal", &missing)
+	indices.AmountAccount = findColumn(header, cn.AmountAccount, "amountAccount", &missing)
+	indices.AmountDebit = findColumn(header, cn.AmountDebit, "amountDebit", &missing)
+	indices.AmountCredit = findColumn(header, cn.AmountCredit, "amountCredit", &missing)
+	indices.Direction = findColumn(header, cn.Direction, "direction", &missing)
+	indices.Fee = findColumn(header, cn.Fee, "fee", &missing)
+	indices.ReceiverAccountNumber = findColumn(header, cn.ReceiverAccountNumber, "receiverAccountNumber", &missing)
+	indices.ReceiverBankCode = findColumn(header, cn.ReceiverBankCode, "receiverBankCode", &missing)
+	indices.NoteForMe = findColumn(header, cn.NoteForMe, "noteForMe", &missing)
+	indices.NoteForReceiver = findColumn(header, cn.NoteForReceiver, "noteForReceiver", &missing)
+	indices.VariableSymbol = findColumn(header, cn.VariableSymbol, "variableSymbol", &missing)
+	indices.ConstantSymbol = findColumn(header, cn.ConstantSymbol, "constantSymbol", &missing)
+	indices.SpecificSymbol = findColumn(header, cn.SpecificSymbol, "specificSymbol", &missing)
+
+	if len(missing) > 0 {
+		return indices, fmt.Errorf("columns not found in header: %s", strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// Resolve CustomColumns to indices in the header
+func (b Bank) CustomNamesToIndices(header []string) (map[string]int, error) {
+	var missing []string
+	indices := make(map[string]int)
+
+	for field, name := range b.CustomColumns {
+		indices[field] = findColumn(header, name, "customColumns."+field, &missing)
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return indices, fmt.Errorf("columns not found in header: %s", strings.Join(missing, ", "))
+	}
+
+	return indices, nil
+}
+
+// check if leading columns in order correspond to identifying columns.
+// The names are compared like in NamesToIndices, see
+// NormalizeColumnName.
+func (b Bank) MatchesHeader(header []string) bool {
+	if len(b.IdentifyingColumns) == 0 {
+		return false
+	}
+
+	if len(b.IdentifyingColumns) > len(header) {
+		return false
+	}
+
+	for i, v := range b.IdentifyingColumns {
+		if NormalizeColumnName(header[i]) != NormalizeColumnName(v) {
+			return false
 		}
-		if v == b.ColumnNames.Fee {
-			indices.Fee = i
+	}
+
+	return true
+}
+
+// Find the index of the header row.  The header is expected right
+// after SkipRows, unless HeaderSearchRows is set, in which case we
+// look for the first row matching IdentifyingColumns.  The second
+// return value is false if no row matched.
+func (b Bank) FindHeaderRow(records [][]string) (int, bool) {
+	searchRows := b.HeaderSearchRows
+	if searchRows == 0 {
+		searchRows = 1
+	}
+
+	for i := b.SkipRows; i < b.SkipRows+searchRows && i < len(records); i++ {
+		if b.MatchesHeader(records[i]) {
+			return i, true
 		}
-		if v == b.ColumnNames.ReceiverAccountNumber {
-			indices.ReceiverAccountNumber = i
+	}
+
+	return b.SkipRows, false
+}
+
+// Parse the rows preceding the header as key-value pairs.  The key is
+// the first non-empty cell (with a trailing colon removed), the value
+// is the rest of the non-empty cells joined by a space.  Single-cell
+// rows of form "key: value" are split on the first colon.
+func ParsePreamble(rows [][]string) map[string]string {
+	preamble := make(map[string]string)
+
+	for _, row := range rows {
+		var cells []string
+		for _, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if cell != "" {
+				cells = append(cells, cell)
+			}
 		}
-		if v == b.ColumnNames.ReceiverBankCode {
-			indices.ReceiverBankCode = i
+
+		if len(cells) == 0 {
+			continue
 		}
-		if v == b.ColumnNames.NoteForMe {
-			indices.NoteForMe = i
+
+		var key, value string
+		if len(cells) == 1 {
+			parts := strings.SplitN(cells[0], ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key, value = parts[0], parts[1]
+		} else {
+			key = cells[0]
+			value = strings.Join(cells[1:], " ")
 		}
-		if v == b.ColumnNames.NoteForReceiver {
-			indices.NoteForReceiver = i
+
+		key = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(key), ":"))
+		if key != "" {
+			preamble[key] = strings.TrimSpace(value)
 		}
 	}
 
-	return indices
+	return preamble
 }
 
-// check if leading columns in order correspond to identifying columns
-func GetBankConfig(header []string, banks map[string]*Bank) (*Bank, bool) {
+// Find the bank whose IdentifyingColumns match the header row.  The
+// header row is searched according to each bank's SkipRows and
+// HeaderSearchRows.  More than one matching bank is an error.  If no
+// configured bank matches, the presets are
+// tried.  A matching preset is returned with an error, as presets do
+// not know the account of the export and the bank must be added to
+// the config.
+func GetBankConfig(records [][]string, banks map[string]*Bank) (*Bank, bool, error) {
+	var matches []string
 	for name, bank := range banks {
-		if len(bank.IdentifyingColumns) == 0 {
-			continue
+		if _, found := bank.FindHeaderRow(records); found {
+			matches = append(matches, name)
 		}
+	}
+	sort.Strings(matches)
 
-		if len(bank.IdentifyingColumns) > len(header) {
-			continue
+	if len(matches) > 1 {
+		return nil, false, fmt.Errorf("the csv file matches the identifyingColumns of banks %s, choose one with --bank-name", strings.Join(matches, ", "))
+	}
+	if len(matches) == 1 {
+		bank := banks[matches[0]]
+		// TODO: should never be empty
+		if bank.Name == "" {
+			bank.Name = matches[0]
 		}
+		return bank, true, nil
+	}
 
-		identifyingColumnsMatch := true
-		for i, v := range bank.IdentifyingColumns {
-			if header[i] != v {
-				identifyingColumnsMatch = false
-				break
-			}
+	for _, name := range PresetNames() {
+		bank, err := GetPreset(name)
+		if err != nil {
+			return nil, false, err
 		}
 
-		if identifyingColumnsMatch {
-			// TODO: should never be empty
-			if bank.Name == "" {
-				bank.Name = name
-			}
-			return bank, true
+		if _, found := bank.FindHeaderRow(records); found {
+			bank.DisplayName = getBankDisplayName(*bank)
+			return bank, true, fmt.Errorf("bank preset %s matches the csv file, add `%s: {preset: %s, accountName: ...}' to the banks in the config", name, name, name)
 		}
 	}
 
-	return &Bank{}, false
+	return &Bank{}, false, nil
+}
+
+// Return the delimiter of the bank's csv export, false if it is to be
+// guessed
+func (b Bank) GetDelimiter() (rune, bool) {
+	if b.Delimiter == "" {
+		return 0, false
+	}
+
+	delimiter, _ := utf8.DecodeRuneInString(b.Delimiter)
+	return delimiter, true
 }
 
 func (b Bank) ValidateBankConfig() bool {
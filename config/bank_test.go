@@ -0,0 +1,175 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func getPreambleRecords() [][]string {
+	return [][]string{
+		{"Account number:", "2000145399/0800", ""},
+		{"Statement period: 01.01.2024 - 31.01.2024"},
+		{"", "", ""},
+		{"Date", "Amount", "Payee"},
+		{"01.01.2024", "-100", "Tesco"},
+		{"Total", "-100", ""},
+	}
+}
+
+func TestFindHeaderRow_with_skipRows(t *testing.T) {
+	bank := Bank{
+		SkipRows:           3,
+		IdentifyingColumns: []string{"Date", "Amount"},
+	}
+
+	row, found := bank.FindHeaderRow(getPreambleRecords())
+
+	assert.True(t, found)
+	assert.Equal(t, 3, row)
+}
+
+func TestFindHeaderRow_with_headerSearchRows(t *testing.T) {
+	bank := Bank{
+		HeaderSearchRows:   10,
+		IdentifyingColumns: []string{"Date", "Amount"},
+	}
+
+	row, found := bank.FindHeaderRow(getPreambleRecords())
+
+	assert.True(t, found)
+	assert.Equal(t, 3, row)
+}
+
+func TestFindHeaderRow_not_in_searched_rows(t *testing.T) {
+	bank := Bank{
+		HeaderSearchRows:   2,
+		IdentifyingColumns: []string{"Date", "Amount"},
+	}
+
+	row, found := bank.FindHeaderRow(getPreambleRecords())
+
+	assert.False(t, found)
+	assert.Equal(t, 0, row)
+}
+
+func TestMatchesHeader_ignoring_case_and_whitespace(t *testing.T) {
+	bank := Bank{IdentifyingColumns: []string{"Datum", "Zpráva pro příjemce"}}
+
+	assert.True(t, bank.MatchesHeader([]string{"\ufeffdatum", " ZPRÁVA  pro příjemce ", "Částka"}))
+	assert.False(t, bank.MatchesHeader([]string{"Datum", "Zpráva"}))
+}
+
+func TestGetBankConfig_with_preamble(t *testing.T) {
+	banks := map[string]*Bank{
+		"first": {
+			IdentifyingColumns: []string{"Date", "Amount"},
+		},
+		"second": {
+			HeaderSearchRows:   5,
+			IdentifyingColumns: []string{"Date", "Amount"},
+		},
+	}
+
+	bank, exists, err := GetBankConfig(getPreambleRecords(), banks)
+
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "second", bank.Name)
+}
+
+func TestGetBankConfig_ambiguous(t *testing.T) {
+	banks := map[string]*Bank{
+		"checking": {HeaderSearchRows: 5, IdentifyingColumns: []string{"Date", "Amount"}},
+		"savings":  {HeaderSearchRows: 5, IdentifyingColumns: []string{"Date"}},
+	}
+
+	_, exists, err := GetBankConfig(getPreambleRecords(), banks)
+
+	assert.False(t, exists)
+	assert.EqualError(t, err, "the csv file matches the identifyingColumns of banks checking, savings, choose one with --bank-name")
+}
+
+func TestParsePreamble(t *testing.T) {
+	preamble := ParsePreamble(getPreambleRecords()[:3])
+
+	assert.Equal(t, map[string]string{
+		"Account number":   "2000145399/0800",
+		"Statement period": "01.01.2024 - 31.01.2024",
+	}, preamble)
+}
+
+func TestUnmarshalColumnNames_with_aliases(t *testing.T) {
+	yamlData := `
+dateRaw: Datum
+payeeRaw: [Zpráva pro příjemce, Zpráva pro příjemce platby]
+`
+
+	var names ColumnNames
+	err := yaml.Unmarshal([]byte(yamlData), &names)
+	if err != nil {
+		t.Fatalf("Error unmarshalling YAML: %v", err)
+	}
+
+	assert.Equal(t, ColumnName{"Datum"}, names.DateRaw)
+	assert.Equal(t, ColumnName{"Zpráva pro příjemce", "Zpráva pro příjemce platby"}, names.PayeeRaw)
+}
+
+func TestNamesToIndices_matches_alias_ignoring_case_and_whitespace(t *testing.T) {
+	bank := Bank{
+		ColumnNames: ColumnNames{
+			DateRaw:       ColumnName{"Datum"},
+			PayeeRaw:      ColumnName{"Zpráva pro příjemce", "Zpráva pro příjemce platby"},
+			AmountAccount: ColumnName{"Částka"},
+		},
+	}
+
+	indices, err := bank.NamesToIndices([]string{"\ufeffdatum", " ZPRÁVA  pro příjemce platby ", "Částka"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, indices.DateRaw)
+	assert.Equal(t, 1, indices.PayeeRaw)
+	assert.Equal(t, 2, indices.AmountAccount)
+	assert.Equal(t, -1, indices.NoteForMe)
+}
+
+func TestNamesToIndices_missing_column(t *testing.T) {
+	bank := Bank{
+		ColumnNames: ColumnNames{
+			DateRaw:       ColumnName{"Datum"},
+			PayeeRaw:      ColumnName{"Zpráva pro příjemce"},
+			AmountAccount: ColumnName{"Částka"},
+		},
+	}
+
+	_, err := bank.NamesToIndices([]string{"Datum", "Zpráva", "Částka"})
+
+	assert.EqualError(t, err, "columns not found in header: payeeRaw (Zpráva pro příjemce)")
+}
+
+func TestReadConfig_delimiter(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, `
+banks:
+  tabs:
+    delimiter: "\t"
+  guessed: {}
+`))
+	assert.Nil(t, err)
+
+	delimiter, exists := config.Banks["tabs"].GetDelimiter()
+	assert.True(t, exists)
+	assert.Equal(t, '\t', delimiter)
+
+	_, exists = config.Banks["guessed"].GetDelimiter()
+	assert.False(t, exists)
+
+	_, err = ReadConfig(writeConfig(t, `
+banks:
+  tabs:
+    delimiter: tab
+`))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "delimiter must be a single character"), err.Error())
+}
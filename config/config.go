@@ -2,8 +2,9 @@ package config
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
+	"strconv"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,6 +24,8 @@ type Config struct {
 
 	Payees map[string]*Payee `yaml:"payees"`
 
+	// Legacy mappings, folded into Payees when the config is read,
+	// see foldLegacy
 	ToPayeeRaw struct {
 		Pattern map[string]string `yaml:"pattern"`
 	} `yaml:"toPayeeRaw"`
@@ -39,13 +42,37 @@ type Config struct {
 
 	ToMeta ToMetaConfig `yaml:"toMeta"`
 
+	// Payees belonging to the travel group, see TravelGroup
 	PayeeIsTravel []string `yaml:"payeeIsTravel"`
 
+	Groups map[string]*Group `yaml:"groups"`
+
+	// Reminders attached to the matching transactions
+	Notes []NoteRule `yaml:"notes"`
+
+	// Marker preceding the notes in the transaction header, see
+	// DefaultNoteMarker
+	NoteMarker *string `yaml:"noteMarker"`
+
 	Currencies struct {
 		SymbolMap map[string]SymbolMap `yaml:"symbolMap"`
 	} `yaml:"currencies"`
 
 	Banks map[string]*Bank `yaml:"banks"`
+
+	Tests []ConfigTest `yaml:"tests"`
+
+	// Source of each key in the config files by its dotted path,
+	// see configFiles.recordPaths
+	sources map[string]Source
+
+	// The config file and the files it includes
+	files []string
+}
+
+// Return the config file and the files it includes
+func (c Config) Files() []string {
+	return c.files
 }
 
 func getBankDisplayName(bank Bank) string {
@@ -64,11 +91,9 @@ func getBankDisplayName(bank Bank) string {
 	return bankDisplayName
 }
 
-// Provide default values for column indices
-func (ci *ColumnIndices) UnmarshalYAML(value *yaml.Node) error {
-	type columnIndices ColumnIndices
-
-	ind := columnIndices{
+// Column indices with all columns marked as not present
+func NewColumnIndices() ColumnIndices {
+	return ColumnIndices{
 		DateRaw:               -1,
 		PayeeRaw:              -1,
 		CurrencyRaw:           -1,
@@ -81,9 +106,23 @@ func (ci *ColumnIndices) UnmarshalYAML(value *yaml.Node) error {
 		AmountAccount:         -1,
 		Fee:                   -1,
 		ReceiverAccountNumber: -1,
+		ReceiverBankCode:      -1,
 		NoteForMe:             -1,
 		NoteForReceiver:       -1,
+		AmountDebit:           -1,
+		AmountCredit:          -1,
+		Direction:             -1,
+		VariableSymbol:        -1,
+		ConstantSymbol:        -1,
+		SpecificSymbol:        -1,
 	}
+}
+
+// Provide default values for column indices
+func (ci *ColumnIndices) UnmarshalYAML(value *yaml.Node) error {
+	type columnIndices ColumnIndices
+
+	ind := columnIndices(NewColumnIndices())
 
 	if err := value.Decode(&ind); err != nil {
 		return err
@@ -94,33 +133,49 @@ func (ci *ColumnIndices) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-func LoadConfig(fileName string) Config {
-	var cfg Config
-
-	yamlFile, err := ioutil.ReadFile(fileName)
+// Column name can be a single string or a list of aliases
+func (cn *ColumnName) UnmarshalYAML(value *yaml.Node) error {
+	var name string
+	if err := value.Decode(&name); err == nil {
+		*cn = ColumnName{name}
+		return nil
+	}
 
-	if err != nil {
-		panic(err)
+	var names []string
+	if err := value.Decode(&names); err != nil {
+		return err
 	}
 
-	err = yaml.Unmarshal(yamlFile, &cfg)
+	*cn = ColumnName(names)
+
+	return nil
+}
+
+// Read and resolve the config file and the files it includes
+func ReadConfig(fileName string) (Config, error) {
+	var cfg Config
+
+	files := newConfigFiles()
+	root, err := files.read(fileName)
 	if err != nil {
-		panic(err)
+		return cfg, err
 	}
 
-	for k, v := range cfg.ToPayeeRaw.Pattern {
-		_, exists := cfg.ToPayee.PayeeRaw[v]
-
-		if !exists {
-			// check if it maps to an account directly
-			_, exists = cfg.ToAccountTo.Payee[v]
-		}
+	if err := files.resolveExtends(root); err != nil {
+		return cfg, err
+	}
+	if err := files.applyPresets(root); err != nil {
+		return cfg, err
+	}
 
-		if !exists {
-			panic(fmt.Sprintf("Regexp-mapped raw-payee %s (from %s) does not exist in payee-raw to payee map ", v, k))
-		}
+	if err := root.Decode(&cfg); err != nil {
+		return cfg, err
 	}
 
+	cfg.files = files.fileNames
+	cfg.sources = make(map[string]Source)
+	files.recordPaths(root, "", cfg.sources)
+
 	for name, payee := range cfg.Payees {
 		payee.Name = name
 	}
@@ -129,29 +184,68 @@ func LoadConfig(fileName string) Config {
 		cfg.Payees = make(map[string]*Payee)
 	}
 
+	cfg.foldLegacy()
+
+	for name, bank := range cfg.Banks {
+		if bank.Abstract {
+			delete(cfg.Banks, name)
+		}
+	}
+
 	for name, bank := range cfg.Banks {
 		bank.Name = name
 		bank.DisplayName = getBankDisplayName(*bank)
 
+		if bank.Delimiter != "" && utf8.RuneCountInString(bank.Delimiter) != 1 {
+			return cfg, fmt.Errorf("%s: bank %s: delimiter must be a single character, not `%s'", cfg.source("banks", name, "delimiter"), name, bank.Delimiter)
+		}
+
 		if bank.PayeeName != "" {
-			p, exists := cfg.Payees[bank.PayeeName]
-			if exists {
+			// undefined payees are reported by Lint
+			if p, exists := cfg.Payees[bank.PayeeName]; exists {
 				bank.Payee = p
-			}
-			if p.Account == "" {
-				p.Account = bank.AccountName
+				if p.Account == "" {
+					p.Account = bank.AccountName
+				}
 			}
 		}
 	}
 
 	MapPayees(cfg.Accounts, "", cfg.Payees)
 
+	files.setSources(root, &cfg)
+
+	for name, payee := range cfg.Payees {
+		for i := range payee.Accounts {
+			payee.Accounts[i].Source = cfg.source("payees", name, "accounts", strconv.Itoa(i))
+		}
+	}
+
+	for i := range cfg.Notes {
+		cfg.Notes[i].Source = cfg.source("notes", strconv.Itoa(i))
+	}
+
+	cfg.resolveGroups()
+
+	for i := range cfg.Tests {
+		cfg.Tests[i].Source = cfg.source("tests", strconv.Itoa(i))
+	}
+
+	return cfg, nil
+}
+
+func LoadConfig(fileName string) Config {
+	cfg, err := ReadConfig(fileName)
+	if err != nil {
+		panic(err)
+	}
+
 	return cfg
 }
 
 func (c Config) ValidateConfig() bool {
 	for _, payee := range c.Payees {
-		if payee.Account == "" && payee.AccountTemplate == "" {
+		if !payee.HasAccount() {
 			fmt.Fprintf(os.Stderr, "Payee `%s' has no assigned account\n", payee.Name)
 		}
 	}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Field derived from one or more columns using a regular expression.
+// For example, card payments often put the merchant, city and the
+// original amount into a single column:
+//
+//	derivedFields:
+//	  - columns: [Detail]
+//	    pattern: 'Nákup: (?P<merchant>[^,]+), .*částka (?P<amount>[0-9.]+) (?P<currency>[A-Z]{3})'
+//	    fields:
+//	      payeeRaw: ${merchant}
+//	      amountReal: ${amount}
+//	      currencyRaw: ${currency}
+type DerivedField struct {
+	// Names of the source columns.  Values of multiple columns are
+	// joined with Separator before matching.
+	Columns []ColumnName `yaml:"columns"`
+
+	// Indices of the source columns, used for files without a
+	// header.  Resolved from Columns when the header is read.
+	ColumnIndices []int `yaml:"columnIndices"`
+
+	// Separator used to join multiple columns, defaults to a space
+	Separator *string `yaml:"separator"`
+
+	// Regular expression matched against the joined columns
+	Pattern string `yaml:"pattern"`
+
+	// Map of the transaction field to the expansion of the match,
+	// for example `$1` or `${merchant}`.  Fields are only set when
+	// the pattern matches and the expansion is not empty.  Names
+	// other than the standard transaction fields (payeeRaw,
+	// amountReal, noteForMe...) are stored as custom fields.  Names
+	// close to a standard field are rejected as likely typos, use
+	// `custom.<name>` for such custom fields.
+	Fields map[string]string `yaml:"fields"`
+
+	// Compiled Pattern
+	Regexp *regexp.Regexp `yaml:"-"`
+}
+
+// Transaction fields which can be set by derived fields
+var derivedFieldTargets = []string{
+	"dateRaw",
+	"payeeRaw",
+	"currencyRaw",
+	"currencyAccount",
+	"paymentType",
+	"commodity",
+	"amountReal",
+	"amountAccount",
+	"fee",
+	"receiverAccountNumber",
+	"noteForMe",
+	"noteForReceiver",
+	"variableSymbol",
+	"constantSymbol",
+	"specificSymbol",
+}
+
+// Prefix of derived field names forcing a custom field
+const CustomFieldPrefix = "custom."
+
+// Check the target of a derived field, names which are not standard
+// fields but close to one are most likely misspelled
+func checkDerivedFieldTarget(field string) error {
+	if strings.HasPrefix(field, CustomFieldPrefix) {
+		return nil
+	}
+
+	for _, target := range derivedFieldTargets {
+		if field == target {
+			return nil
+		}
+	}
+
+	for _, target := range derivedFieldTargets {
+		if editDistance(strings.ToLower(field), strings.ToLower(target)) < 3 {
+			return fmt.Errorf("sets unknown field %s, did you mean %s? Use %s%s for a custom field", field, target, CustomFieldPrefix, field)
+		}
+	}
+
+	return nil
+}
+
+func (df DerivedField) GetSeparator() string {
+	if df.Separator == nil {
+		return " "
+	}
+
+	return *df.Separator
+}
+
+// Resolve column names of derived fields to indices and compile the
+// patterns.  Header can be nil if the derived fields use column
+// indices.
+func (b *Bank) ResolveDerivedFields(header []string) error {
+	for i := range b.DerivedFields {
+		df := &b.DerivedFields[i]
+
+		if len(df.Columns) > 0 {
+			var missing []string
+			df.ColumnIndices = make([]int, len(df.Columns))
+			for j, name := range df.Columns {
+				df.ColumnIndices[j] = findColumn(header, name, fmt.Sprintf("derivedFields[%d].columns[%d]", i, j), &missing)
+			}
+
+			if len(missing) > 0 {
+				return fmt.Errorf("columns not found in header: %s", strings.Join(missing, ", "))
+			}
+		}
+
+		if len(df.ColumnIndices) == 0 {
+			return fmt.Errorf("derivedFields[%d] has no source columns", i)
+		}
+
+		for _, field := range sortedKeys(df.Fields) {
+			if err := checkDerivedFieldTarget(field); err != nil {
+				return fmt.Errorf("derivedFields[%d] %s", i, err)
+			}
+		}
+
+		re, err := regexp.Compile(df.Pattern)
+		if err != nil {
+			return fmt.Errorf("derivedFields[%d] has invalid pattern: %s", i, err)
+		}
+		df.Regexp = re
+	}
+
+	return nil
+}
+
+// Match the derived field against a csv record.  Returns a map of
+// field name to value for all non-empty expansions.
+func (df DerivedField) Extract(record []string) map[string]string {
+	values := make([]string, 0, len(df.ColumnIndices))
+	for _, index := range df.ColumnIndices {
+		if index >= 0 && index < len(record) {
+			values = append(values, record[index])
+		}
+	}
+
+	source := strings.Join(values, df.GetSeparator())
+	match := df.Regexp.FindStringSubmatchIndex(source)
+	if match == nil {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for field, template := range df.Fields {
+		value := string(df.Regexp.ExpandString(nil, template, source, match))
+		if value != "" {
+			result[field] = value
+		}
+	}
+
+	return result
+}
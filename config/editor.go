@@ -0,0 +1,719 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Editor of the config file working on the yaml nodes, so comments,
+// key order and the shorthand forms of payees are preserved.  Only
+// the entries being changed are encoded again and spliced into the
+// original text, all other lines are kept byte for byte.
+type ConfigEditor struct {
+	fileName string
+	content  []byte
+	doc      yaml.Node
+	indent   int
+
+	// Editors of the files included by the config file, payees and
+	// accounts are edited in the file they are defined in
+	included []*ConfigEditor
+
+	// Key of each mapping value and the parent of each node of the
+	// original document, new nodes are in neither
+	keys    map[*yaml.Node]*yaml.Node
+	parents map[*yaml.Node]*yaml.Node
+
+	// Original nodes whose content was replaced, the rewrite of
+	// their entry includes all changes inside them
+	replaced map[*yaml.Node]bool
+
+	edits []edit
+
+	// Encode the whole document, used when the top level is not a
+	// block mapping
+	rewriteAll bool
+}
+
+// Change of the original document: either the entry (or the sequence
+// item) of node is encoded again, or the nodes are inserted after the
+// last original child of the container
+type edit struct {
+	node      *yaml.Node
+	container *yaml.Node
+	inserted  []*yaml.Node
+}
+
+// Open the config file and the files it includes for editing
+func OpenConfigEditor(fileName string) (*ConfigEditor, error) {
+	editor, err := openFileEditor(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// resolve the includes the same way as when reading the config
+	files := newConfigFiles()
+	if _, err := files.read(fileName); err != nil {
+		return nil, err
+	}
+
+	for _, included := range files.fileNames[1:] {
+		includedEditor, err := openFileEditor(included)
+		if err != nil {
+			return nil, err
+		}
+		editor.included = append(editor.included, includedEditor)
+	}
+
+	return editor, nil
+}
+
+func openFileEditor(fileName string) (*ConfigEditor, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	editor := &ConfigEditor{
+		fileName: fileName,
+		content:  content,
+		indent:   detectIndent(content),
+		keys:     make(map[*yaml.Node]*yaml.Node),
+		parents:  make(map[*yaml.Node]*yaml.Node),
+		replaced: make(map[*yaml.Node]bool),
+	}
+
+	if err := yaml.Unmarshal(content, &editor.doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+
+	if editor.doc.Kind == 0 || len(editor.doc.Content) == 0 {
+		editor.doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	if editor.root().Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: top level is not a mapping", fileName)
+	}
+	editor.rewriteAll = editor.root().Style&yaml.FlowStyle != 0
+
+	editor.recordParents(editor.root())
+
+	return editor, nil
+}
+
+func (e *ConfigEditor) recordParents(node *yaml.Node) {
+	for i, child := range node.Content {
+		e.parents[child] = node
+		if node.Kind == yaml.MappingNode && i%2 == 1 {
+			e.keys[child] = node.Content[i-1]
+		}
+		e.recordParents(child)
+	}
+}
+
+// Use the indentation of the first indented line, so the encoded
+// entries are indented like the rest of the file
+func detectIndent(content []byte) int {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+
+		if indent := len(line) - len(trimmed); indent > 0 {
+			return indent
+		}
+	}
+
+	return 2
+}
+
+func (e *ConfigEditor) root() *yaml.Node {
+	return e.doc.Content[0]
+}
+
+func (e *ConfigEditor) isOriginal(node *yaml.Node) bool {
+	_, exists := e.parents[node]
+	return exists || node == e.root()
+}
+
+// Record that the content of node changed.  Nodes in flow style are
+// encoded together with the enclosing block entry.
+func (e *ConfigEditor) changed(node *yaml.Node) {
+	if !e.isOriginal(node) {
+		return
+	}
+
+	for node != e.root() && e.parents[node].Style&yaml.FlowStyle != 0 {
+		node = e.parents[node]
+	}
+
+	if node == e.root() {
+		e.rewriteAll = true
+		return
+	}
+
+	e.edits = append(e.edits, edit{node: node})
+}
+
+// Replace the content of node, e.g. convert a scalar to a list
+func (e *ConfigEditor) replace(node *yaml.Node, value yaml.Node) {
+	e.changed(node)
+	e.replaced[node] = true
+	*node = value
+}
+
+// Append nodes to the content of the mapping or sequence.  Appending
+// to a block container is an insertion after its last child, flow and
+// empty containers are encoded again.
+func (e *ConfigEditor) appendNodes(container *yaml.Node, nodes ...*yaml.Node) {
+	original := e.isOriginal(container) && !e.replaced[container]
+	empty := len(container.Content) == 0
+	container.Content = append(container.Content, nodes...)
+
+	if !original {
+		return
+	}
+
+	if container.Style&yaml.FlowStyle != 0 || empty && container != e.root() {
+		e.changed(container)
+		e.replaced[container] = true
+		return
+	}
+
+	for i := range e.edits {
+		if e.edits[i].container == container {
+			e.edits[i].inserted = append(e.edits[i].inserted, nodes...)
+			return
+		}
+	}
+	e.edits = append(e.edits, edit{container: container, inserted: nodes})
+}
+
+// Lines of the original content, the last one is empty if the
+// content ends with a newline
+func (e *ConfigEditor) lines() []string {
+	return strings.Split(string(e.content), "\n")
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// Index of the line after the block starting at line start, which
+// contains the following lines indented more than indent.  If
+// sequenceValue is set, `- ` lines at the same indentation belong to
+// the block too.  Trailing blank and comment lines are not part of
+// the block.
+func blockEnd(lines []string, start int, indent int, sequenceValue bool) int {
+	end := start + 1
+
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankOrComment(line) {
+			continue
+		}
+
+		lineIndent := lineIndent(line)
+		if lineIndent > indent || lineIndent == indent && sequenceValue && strings.HasPrefix(strings.TrimSpace(line), "-") {
+			end = i + 1
+			continue
+		}
+
+		break
+	}
+
+	return end
+}
+
+// Position of the node's entry: the line of the key for mapping
+// values, of the item for sequence items, and the column where the
+// entry (or the dash of the item) starts
+func (e *ConfigEditor) entryPosition(lines []string, node *yaml.Node) (int, int) {
+	if key, exists := e.keys[node]; exists {
+		return key.Line - 1, key.Column - 1
+	}
+
+	line, column := node.Line-1, node.Column-1
+	dash := strings.LastIndex(lines[line][:column], "-")
+	return line, dash
+}
+
+// Range of lines of the node's entry
+func (e *ConfigEditor) entryRange(lines []string, node *yaml.Node) (int, int) {
+	line, column := e.entryPosition(lines, node)
+	_, isEntry := e.keys[node]
+
+	return line, blockEnd(lines, line, column, isEntry && node.Kind == yaml.SequenceNode)
+}
+
+// Copy of the node without the comments which stay in the original
+// text: the head comments of the first nodes precede the entry and the
+// foot comments of the last nodes follow it
+func withoutOuterComments(node *yaml.Node) *yaml.Node {
+	return clearComments(clearComments(node, 0, true), -1, false)
+}
+
+// Copy the chain of the first (index 0) or last (index -1) children
+// and clear their head or foot comments
+func clearComments(node *yaml.Node, index int, head bool) *yaml.Node {
+	result := *node
+	if head {
+		result.HeadComment = ""
+	} else {
+		result.FootComment = ""
+	}
+
+	if len(result.Content) > 0 {
+		i := index
+		if i < 0 {
+			i = len(result.Content) - 1
+		}
+		result.Content = append([]*yaml.Node{}, result.Content...)
+		result.Content[i] = clearComments(result.Content[i], index, head)
+	}
+
+	return &result
+}
+
+func (e *ConfigEditor) encode(node *yaml.Node) ([]string, error) {
+	var out bytes.Buffer
+
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(e.indent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	encoder.Close()
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), nil
+}
+
+// Prefix the first line with first and the others with spaces of the
+// same length
+func prefixLines(lines []string, first string) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		prefix := first
+		if i > 0 {
+			prefix = strings.Repeat(" ", len(first))
+		}
+		result[i] = prefix + line
+	}
+
+	return result
+}
+
+// Lines replacing lines[start:end] of the original content
+type splice struct {
+	start int
+	end   int
+	lines []string
+}
+
+// Encode the entry of node, keeping the text preceding it on its line
+// such as the dash of a sequence item
+func (e *ConfigEditor) rewriteSplice(lines []string, node *yaml.Node) (splice, error) {
+	start, end := e.entryRange(lines, node)
+
+	fragment := withoutOuterComments(node)
+	if key, exists := e.keys[node]; exists {
+		fragment = withoutOuterComments(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, node}})
+	}
+
+	encoded, err := e.encode(fragment)
+	if err != nil {
+		return splice{}, err
+	}
+
+	_, column := e.entryPosition(lines, node)
+	if _, exists := e.keys[node]; !exists {
+		// item after the dash
+		column = node.Column - 1
+	}
+
+	return splice{start, end, prefixLines(encoded, lines[start][:column])}, nil
+}
+
+// Encode the inserted nodes after the last original child of the
+// container, indented like it
+func (e *ConfigEditor) insertSplice(lines []string, ed edit) (splice, error) {
+	children := ed.container.Content[:len(ed.container.Content)-len(ed.inserted)]
+
+	position, indent := len(lines), 0
+	for position > 0 && strings.TrimSpace(lines[position-1]) == "" {
+		position--
+	}
+	if len(children) > 0 {
+		last := children[len(children)-1]
+		_, indent = e.entryPosition(lines, last)
+		_, position = e.entryRange(lines, last)
+	}
+
+	container := *ed.container
+	container.Content = ed.inserted
+	container.Style = 0
+	container.HeadComment, container.LineComment, container.FootComment = "", "", ""
+
+	encoded, err := e.encode(&container)
+	if err != nil {
+		return splice{}, err
+	}
+
+	return splice{position, position, prefixLines(encoded, strings.Repeat(" ", indent))}, nil
+}
+
+func (e *ConfigEditor) Bytes() ([]byte, error) {
+	if e.rewriteAll {
+		lines, err := e.encode(&e.doc)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	}
+
+	lines := e.lines()
+
+	var splices []splice
+	for _, ed := range e.edits {
+		var sp splice
+		var err error
+		if ed.container != nil {
+			sp, err = e.insertSplice(lines, ed)
+		} else {
+			sp, err = e.rewriteSplice(lines, ed.node)
+		}
+		if err != nil {
+			return nil, err
+		}
+		splices = append(splices, sp)
+	}
+
+	// Insertions go before rewrites starting on the same line, as
+	// they follow the preceding block.  Of the rewrites starting on
+	// the same line the outer one goes first.
+	sort.SliceStable(splices, func(i, j int) bool {
+		a, b := splices[i], splices[j]
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		if (a.start == a.end) != (b.start == b.end) {
+			return a.start == a.end
+		}
+		return a.end > b.end
+	})
+
+	var out []string
+	position := 0
+	for _, sp := range splices {
+		// changes inside a rewritten entry are part of the rewrite
+		if sp.start < position {
+			continue
+		}
+		out = append(out, lines[position:sp.start]...)
+		out = append(out, sp.lines...)
+		position = sp.end
+	}
+	out = append(out, lines[position:]...)
+
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// Save the config file and the included files that changed
+func (e *ConfigEditor) Save() error {
+	for _, file := range e.files() {
+		if err := file.save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *ConfigEditor) save() error {
+	content, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(content, e.content) {
+		return nil
+	}
+
+	info, err := os.Stat(e.fileName)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(e.fileName, content, info.Mode())
+}
+
+func newScalar(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+
+	// quote regular expressions so the special characters do not
+	// clash with yaml syntax
+	if regexp.QuoteMeta(value) != value || strings.ContainsAny(value, ":#") {
+		node.Style = yaml.SingleQuotedStyle
+	}
+
+	return node
+}
+
+// Return the value node of key in the mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// Return the value node of key in the mapping, adding the key with
+// the given value if it does not exist
+func (e *ConfigEditor) ensureMappingValue(mapping *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	if existing := mappingValue(mapping, key); existing != nil {
+		return existing
+	}
+
+	e.appendNodes(mapping, newScalar(key), value)
+	return value
+}
+
+// Return the mapping under key, adding it if it does not exist.  An
+// empty value, e.g. `payees:`, is replaced by a mapping.
+func (e *ConfigEditor) ensureMapping(mapping *yaml.Node, key string) *yaml.Node {
+	node := e.ensureMappingValue(mapping, key, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+	if isNull(node) {
+		e.replace(node, yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: node.LineComment})
+	}
+
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func sequenceContains(sequence *yaml.Node, value string) bool {
+	for _, item := range sequence.Content {
+		if item.Kind == yaml.ScalarNode && item.Value == value {
+			return true
+		}
+		// pattern with meta, `- pattern: {meta}`
+		if item.Kind == yaml.MappingNode && len(item.Content) > 0 && item.Content[0].Value == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add value to a node holding a string or a list of strings.  Single
+// strings are converted to a list, keeping the original item.
+func (e *ConfigEditor) appendToList(node *yaml.Node, value string) {
+	switch {
+	case isNull(node):
+		e.replace(node, *newScalar(value))
+	case node.Kind == yaml.ScalarNode:
+		if node.Value == value {
+			return
+		}
+		first := *node
+		first.HeadComment, first.LineComment, first.FootComment = "", "", ""
+		e.replace(node, yaml.Node{
+			Kind:        yaml.SequenceNode,
+			Tag:         "!!seq",
+			Style:       yaml.FlowStyle,
+			Content:     []*yaml.Node{&first, newScalar(value)},
+			HeadComment: node.HeadComment,
+			LineComment: node.LineComment,
+			FootComment: node.FootComment,
+		})
+	case node.Kind == yaml.SequenceNode:
+		if !sequenceContains(node, value) {
+			e.appendNodes(node, newScalar(value))
+		}
+	}
+}
+
+func (e *ConfigEditor) payees() *yaml.Node {
+	return e.ensureMapping(e.root(), "payees")
+}
+
+// The config file followed by the files it includes
+func (e *ConfigEditor) files() []*ConfigEditor {
+	return append([]*ConfigEditor{e}, e.included...)
+}
+
+// Return the editor of the file defining the payee in its payees
+// section, or nil
+func (e *ConfigEditor) payeeFile(name string) *ConfigEditor {
+	for _, file := range e.files() {
+		payees := mappingValue(file.root(), "payees")
+		if payees != nil && payees.Kind == yaml.MappingNode && mappingValue(payees, name) != nil {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// Add a new payee with the given PayeeRaw patterns to the config
+// file.  A single pattern is written in the shorthand `Name: pattern`
+// form.  If account is not empty, the payee is also added to the
+// accounts hierarchy.
+func (e *ConfigEditor) AddPayee(name string, patterns []string, account string) error {
+	if file := e.payeeFile(name); file == e {
+		return fmt.Errorf("payee `%s' already exists", name)
+	} else if file != nil {
+		return fmt.Errorf("payee `%s' already exists in %s", name, file.fileName)
+	}
+
+	var value *yaml.Node
+	if len(patterns) == 1 {
+		value = newScalar(patterns[0])
+	} else {
+		value = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, pattern := range patterns {
+			value.Content = append(value.Content, newScalar(pattern))
+		}
+	}
+
+	e.appendNodes(e.payees(), newScalar(name), value)
+
+	if account != "" {
+		return e.AddPayeeToAccount(account, name)
+	}
+
+	return nil
+}
+
+// Append a PayeeRaw pattern to an existing payee, in whichever form
+// and file the payee is written
+func (e *ConfigEditor) AddPattern(name string, pattern string) error {
+	file := e.payeeFile(name)
+	if file == nil {
+		return fmt.Errorf("payee `%s' does not exist", name)
+	}
+
+	return file.addPattern(name, pattern)
+}
+
+func (e *ConfigEditor) addPattern(name string, pattern string) error {
+	payee := mappingValue(e.payees(), name)
+
+	switch payee.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		e.appendToList(payee, pattern)
+	case yaml.MappingNode:
+		e.appendToList(e.ensureMappingValue(payee, "payeeRaw", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}), pattern)
+	default:
+		return fmt.Errorf("payee `%s' has unsupported format", name)
+	}
+
+	return nil
+}
+
+// Place the payee under the account in the accounts hierarchy, in the
+// file defining the most of the account's parents.  Missing accounts
+// are created.  Accounts which only listed payees so far are
+// converted to a mapping with the payees under `self`.
+func (e *ConfigEditor) AddPayeeToAccount(account string, payee string) error {
+	segments := strings.Split(account, ":")
+
+	target, depth := e, 0
+	for _, file := range e.files() {
+		if d := file.accountDepth(segments); d > depth {
+			target, depth = file, d
+		}
+	}
+
+	return target.addPayeeToAccount(account, segments, payee)
+}
+
+// Number of the leading account segments defined in the file's
+// accounts hierarchy
+func (e *ConfigEditor) accountDepth(segments []string) int {
+	node := mappingValue(e.root(), "accounts")
+	for depth, segment := range segments {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return depth
+		}
+		if node = mappingValue(node, segment); node == nil {
+			return depth
+		}
+	}
+
+	return len(segments)
+}
+
+func (e *ConfigEditor) addPayeeToAccount(account string, segments []string, payee string) error {
+	node := e.ensureMapping(e.root(), "accounts")
+
+	for i, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("invalid account name `%s'", account)
+		}
+
+		if node.Kind != yaml.MappingNode {
+			// leaf with payees becomes an account with sub-accounts
+			self := *node
+			if isNull(node) {
+				// `Account:` without value is a payee of the same name
+				self = *newScalar(segments[i-1])
+			}
+			self.HeadComment, self.LineComment, self.FootComment = "", "", ""
+			e.replace(node, yaml.Node{
+				Kind:        yaml.MappingNode,
+				Tag:         "!!map",
+				Content:     []*yaml.Node{newScalar("self"), &self},
+				HeadComment: node.HeadComment,
+				LineComment: node.LineComment,
+				FootComment: node.FootComment,
+			})
+		}
+
+		existing := mappingValue(node, segment)
+		if existing == nil {
+			if i == len(segments)-1 {
+				e.appendNodes(node, newScalar(segment), newScalar(payee))
+				return nil
+			}
+			existing = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			e.appendNodes(node, newScalar(segment), existing)
+		}
+
+		node = existing
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode:
+		e.appendToList(e.ensureMappingValue(node, "self", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}), payee)
+	case isNull(node):
+		if segments[len(segments)-1] != payee {
+			e.appendToList(node, segments[len(segments)-1])
+			e.appendToList(node, payee)
+		}
+	default:
+		e.appendToList(node, payee)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openEditor(t *testing.T, content string) *ConfigEditor {
+	fileName := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	editor, err := OpenConfigEditor(fileName)
+	if err != nil {
+		t.Fatalf("Error opening config: %v", err)
+	}
+
+	return editor
+}
+
+func assertEditorOutput(t *testing.T, expected string, editor *ConfigEditor) {
+	out, err := editor.Bytes()
+	if err != nil {
+		t.Fatalf("Error encoding config: %v", err)
+	}
+
+	assert.Equal(t, expected, string(out))
+}
+
+func TestConfigEditor_AddPayee_keeps_comments(t *testing.T) {
+	editor := openEditor(t, `# My config
+payees:
+  # groceries
+  Tesco: '^tesco' # the big one
+`)
+
+	err := editor.AddPayee("Albert", []string{"^albert"}, "")
+	assert.Nil(t, err)
+
+	assertEditorOutput(t, `# My config
+payees:
+  # groceries
+  Tesco: '^tesco' # the big one
+  Albert: '^albert'
+`, editor)
+}
+
+func TestConfigEditor_AddPayee_existing(t *testing.T) {
+	editor := openEditor(t, `payees:
+  Tesco: '^tesco'
+`)
+
+	err := editor.AddPayee("Tesco", []string{"^tesco"}, "")
+	assert.EqualError(t, err, "payee `Tesco' already exists")
+}
+
+func TestConfigEditor_AddPattern_to_shorthand_string(t *testing.T) {
+	editor := openEditor(t, `payees:
+  Tesco: '^tesco' # comment
+`)
+
+	err := editor.AddPattern("Tesco", "^tesco stores")
+	assert.Nil(t, err)
+
+	assertEditorOutput(t, `payees:
+  Tesco: ['^tesco', '^tesco stores'] # comment
+`, editor)
+}
+
+func TestConfigEditor_AddPattern_to_list(t *testing.T) {
+	editor := openEditor(t, `payees:
+  Tesco:
+    - '^tesco'
+`)
+
+	err := editor.AddPattern("Tesco", "^tesco stores")
+	assert.Nil(t, err)
+
+	assertEditorOutput(t, `payees:
+  Tesco:
+    - '^tesco'
+    - '^tesco stores'
+`, editor)
+}
+
+func TestConfigEditor_AddPattern_to_mapping(t *testing.T) {
+	editor := openEditor(t, `payees:
+  Tesco:
+    payeeRaw: '^tesco'
+    meta:
+      location: Prague
+  Albert:
+    meta:
+      location: Brno
+`)
+
+	assert.Nil(t, editor.AddPattern("Tesco", "^tesco stores"))
+	assert.Nil(t, editor.AddPattern("Albert", "^albert"))
+
+	assertEditorOutput(t, `payees:
+  Tesco:
+    payeeRaw: ['^tesco', '^tesco stores']
+    meta:
+      location: Prague
+  Albert:
+    meta:
+      location: Brno
+    payeeRaw: '^albert'
+`, editor)
+}
+
+func TestConfigEditor_AddPayeeToAccount(t *testing.T) {
+	editor := openEditor(t, `accounts:
+  Expenses:
+    # food
+    Groceries: Tesco
+    Restaurant:
+      - Old Mill
+    Healthcare:
+      self: Pharmacy
+    Hotel:
+      Airbnb:
+`)
+
+	assert.Nil(t, editor.AddPayeeToAccount("Expenses:Groceries", "Albert"))
+	assert.Nil(t, editor.AddPayeeToAccount("Expenses:Restaurant", "Qerko"))
+	assert.Nil(t, editor.AddPayeeToAccount("Expenses:Healthcare", "Dentist"))
+	assert.Nil(t, editor.AddPayeeToAccount("Expenses:Hotel:Airbnb", "Booking"))
+	assert.Nil(t, editor.AddPayeeToAccount("Expenses:Groceries:Online", "Rohlik"))
+	assert.Nil(t, editor.AddPayeeToAccount("Income:Salary", "Employer"))
+
+	assertEditorOutput(t, `accounts:
+  Expenses:
+    # food
+    Groceries:
+      self: [Tesco, Albert]
+      Online: Rohlik
+    Restaurant:
+      - Old Mill
+      - Qerko
+    Healthcare:
+      self: [Pharmacy, Dentist]
+    Hotel:
+      Airbnb: [Airbnb, Booking]
+  Income:
+    Salary: Employer
+`, editor)
+}
+
+// Lines of after which are not in before, and whether the lines of
+// before are kept in order
+func addedLines(before string, after string) ([]string, bool) {
+	original := strings.Split(before, "\n")
+	var added []string
+	i := 0
+	for _, line := range strings.Split(after, "\n") {
+		if i < len(original) && line == original[i] {
+			i++
+		} else {
+			added = append(added, line)
+		}
+	}
+
+	return added, i == len(original)
+}
+
+func TestConfigEditor_keeps_layout(t *testing.T) {
+	content := `# My config
+
+payees:
+  Tesco:   '^tesco'      # big one
+  Albert:  '^albert'     # small one
+
+  # online
+  Rohlik:
+    - '^rohlik'
+
+accounts:
+  Expenses:
+    Groceries:
+      - Tesco   # big one
+      - Albert  # small one
+
+    Online: Rohlik
+
+banks:
+  fio: {preset: fio}
+`
+	editor := openEditor(t, content)
+
+	assert.Nil(t, editor.AddPayee("Lidl", []string{"^lidl"}, "Expenses:Groceries"))
+	assert.Nil(t, editor.AddPattern("Rohlik", "^rohlik.cz"))
+
+	out, err := editor.Bytes()
+	assert.Nil(t, err)
+
+	added, kept := addedLines(content, string(out))
+	assert.True(t, kept, string(out))
+	assert.Equal(t, []string{
+		"  Lidl: '^lidl'",
+		"    - '^rohlik.cz'",
+		"      - Lidl",
+	}, added, string(out))
+}
+
+func TestConfigEditor_AddPayee_to_flow_and_missing_sections(t *testing.T) {
+	editor := openEditor(t, `banks:
+
+  fio: {preset: fio}
+payees: {}
+`)
+
+	assert.Nil(t, editor.AddPayee("Lidl", []string{"^lidl"}, "Expenses:Groceries"))
+
+	assertEditorOutput(t, `banks:
+
+  fio: {preset: fio}
+payees: {Lidl: '^lidl'}
+accounts:
+  Expenses:
+    Groceries: Lidl
+`, editor)
+}
+
+func TestConfigEditor_AddPayee_to_empty_section(t *testing.T) {
+	editor := openEditor(t, `payees: # none yet
+
+accounts:
+`)
+
+	assert.Nil(t, editor.AddPayee("Lidl", []string{"^lidl"}, ""))
+
+	assertEditorOutput(t, `payees: # none yet
+  Lidl: '^lidl'
+
+accounts:
+`, editor)
+}
+
+func TestConfigEditor_included_files(t *testing.T) {
+	main := `include: payees/*.yaml
+payees:
+  MyBank: '^mybank$'
+`
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": main,
+		"payees/shops.yaml": `payees:
+  Tesco: '^tesco'
+accounts:
+  Expenses:
+    Groceries: [Tesco]
+`,
+	})
+	shops := filepath.Join(filepath.Dir(fileName), "payees/shops.yaml")
+
+	editor, err := OpenConfigEditor(fileName)
+	assert.Nil(t, err)
+
+	err = editor.AddPayee("Tesco", []string{"^tesco"}, "")
+	assert.Equal(t, "payee `Tesco' already exists in "+shops, err.Error())
+
+	assert.Nil(t, editor.AddPattern("Tesco", "^tesco stores"))
+	assert.Nil(t, editor.AddPayee("Albert", []string{"^albert"}, "Expenses:Groceries"))
+	assert.Nil(t, editor.Save())
+
+	content, _ := ioutil.ReadFile(fileName)
+	assert.Equal(t, main+"  Albert: '^albert'\n", string(content))
+
+	content, _ = ioutil.ReadFile(shops)
+	assert.Equal(t, `payees:
+  Tesco: ['^tesco', '^tesco stores']
+accounts:
+  Expenses:
+    Groceries: [Tesco, Albert]
+`, string(content))
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, "Expenses:Groceries", config.Payees["Albert"].Account)
+	assert.Equal(t, 2, len(config.Payees["Tesco"].PayeeRaw))
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values of Bank.ListMerge
+const (
+	ListMergeReplace = "replace"
+	ListMergeAppend  = "append"
+)
+
+// Replace banks extending another bank with the other bank's settings
+// merged with their own.  Chains are resolved recursively, cycles and
+// unknown banks are errors.  The node is the config root mapping.
+func (cf *configFiles) resolveExtends(root *yaml.Node) error {
+	banks, _ := mappingEntry(root, "banks")
+	banks = resolveAlias(banks)
+	if banks == nil || banks.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	index := make(map[string]int)
+	for i := 0; i+1 < len(banks.Content); i += 2 {
+		index[banks.Content[i].Value] = i + 1
+	}
+
+	resolved := make(map[string]*yaml.Node)
+
+	var resolve func(name string, chain []string) (*yaml.Node, error)
+	resolve = func(name string, chain []string) (*yaml.Node, error) {
+		if node, exists := resolved[name]; exists {
+			return node, nil
+		}
+
+		for i, other := range chain {
+			if other == name {
+				return nil, fmt.Errorf("banks extend each other in a cycle: %s", strings.Join(append(chain[i:], name), " -> "))
+			}
+		}
+
+		own := resolveAlias(banks.Content[index[name]])
+		if own.Kind != yaml.MappingNode {
+			resolved[name] = own
+			return own, nil
+		}
+
+		extends, _ := mappingEntry(own, "extends")
+		if extends == nil || extends.Value == "" {
+			resolved[name] = own
+			return own, nil
+		}
+
+		if _, exists := index[extends.Value]; !exists {
+			return nil, fmt.Errorf("%s: bank %s extends unknown bank `%s'", cf.source(extends), name, extends.Value)
+		}
+
+		appendLists := false
+		if listMerge, _ := mappingEntry(own, "listMerge"); listMerge != nil {
+			switch listMerge.Value {
+			case ListMergeAppend:
+				appendLists = true
+			case ListMergeReplace:
+			default:
+				return nil, fmt.Errorf("%s: bank %s: listMerge must be `%s' or `%s', not `%s'",
+					cf.source(listMerge), name, ListMergeReplace, ListMergeAppend, listMerge.Value)
+			}
+		}
+
+		parent, err := resolve(extends.Value, append(chain, name))
+		if err != nil {
+			return nil, err
+		}
+
+		merged := mergeNodes(parent, own, appendLists)
+		if merged.Kind == yaml.MappingNode {
+			// only the bank's own settings, these are not inherited
+			for _, key := range []string{"abstract", "listMerge"} {
+				if _, index := mappingEntry(own, key); index == -1 {
+					removeMappingEntry(merged, key)
+				}
+			}
+		}
+
+		resolved[name] = merged
+		return merged, nil
+	}
+
+	for i := 0; i+1 < len(banks.Content); i += 2 {
+		node, err := resolve(banks.Content[i].Value, nil)
+		if err != nil {
+			return err
+		}
+		banks.Content[i+1] = node
+	}
+
+	return nil
+}
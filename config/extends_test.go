@@ -0,0 +1,118 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const extendsConfig = `
+banks:
+  fio-base:
+    abstract: true
+    displayName: Fio
+    datePatternFrom: 02.01.2006
+    identifyingColumns: [Datum, Objem]
+    columnNames:
+      dateRaw: Datum
+      amountAccount: Objem
+      payeeRaw: Protiúčet
+    ignoredTransactions:
+      - matchers:
+          - paymentType: Rezervace
+  fio-checking:
+    extends: fio-base
+    accountName: Assets:Fio:Checking
+    fileNamePattern: '^Fio_checking'
+    columnNames:
+      payeeRaw: Název protiúčtu
+  fio-savings:
+    extends: fio-checking
+    listMerge: append
+    accountName: Assets:Fio:Savings
+    fileNamePattern: '^Fio_savings'
+    identifyingColumns: [Datum, Objem, Úrok]
+    ignoredTransactions:
+      - matchers:
+          - paymentType: Úrok
+`
+
+func TestReadConfig_extends(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, extendsConfig))
+	assert.Nil(t, err)
+
+	_, exists := config.Banks["fio-base"]
+	assert.False(t, exists, "abstract banks are removed")
+
+	checking := config.Banks["fio-checking"]
+	assert.Equal(t, "Assets:Fio:Checking", checking.AccountName)
+	assert.Equal(t, "Fio", checking.DisplayName)
+	assert.Equal(t, "02.01.2006", checking.DatePatternFrom)
+	assert.Equal(t, ColumnName{"Datum"}, checking.ColumnNames.DateRaw)
+	assert.Equal(t, ColumnName{"Název protiúčtu"}, checking.ColumnNames.PayeeRaw)
+	assert.Equal(t, 1, len(checking.IgnoredTransactions))
+	assert.False(t, checking.Abstract)
+
+	savings := config.Banks["fio-savings"]
+	assert.Equal(t, "Assets:Fio:Savings", savings.AccountName)
+	assert.Equal(t, "^Fio_savings", savings.FileNamePattern)
+	assert.Equal(t, "Fio", savings.DisplayName)
+	assert.Equal(t, ColumnName{"Název protiúčtu"}, savings.ColumnNames.PayeeRaw)
+	assert.Equal(t, 2, len(savings.IgnoredTransactions))
+	assert.Equal(t, "Rezervace", savings.IgnoredTransactions[0].Matchers[0].PaymentType)
+	assert.Equal(t, "Úrok", savings.IgnoredTransactions[1].Matchers[0].PaymentType)
+	// only the lists of rules are appended
+	assert.Equal(t, []string{"Datum", "Objem", "Úrok"}, savings.IdentifyingColumns)
+}
+
+func TestReadConfig_extends_replaces_lists_by_default(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, strings.Replace(extendsConfig, "    listMerge: append\n", "", 1)))
+	assert.Nil(t, err)
+
+	savings := config.Banks["fio-savings"]
+	assert.Equal(t, 1, len(savings.IgnoredTransactions))
+	assert.Equal(t, "Úrok", savings.IgnoredTransactions[0].Matchers[0].PaymentType)
+}
+
+func TestReadConfig_extends_cycle(t *testing.T) {
+	_, err := ReadConfig(writeConfig(t, `
+banks:
+  a:
+    extends: b
+  b:
+    extends: c
+  c:
+    extends: a
+`))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "cycle"), err.Error())
+}
+
+func TestReadConfig_extends_unknown_bank(t *testing.T) {
+	_, err := ReadConfig(writeConfig(t, `
+banks:
+  a:
+    extends: nonexistent
+`))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "nonexistent"), err.Error())
+}
+
+func TestReadConfig_extends_bank_with_preset(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, `
+banks:
+  fio:
+    preset: fio-csv-2024
+    accountName: Assets:Fio
+  fio-business:
+    extends: fio
+    accountName: Assets:Fio:Business
+`))
+	assert.Nil(t, err)
+
+	bank := config.Banks["fio-business"]
+	assert.Equal(t, "Assets:Fio:Business", bank.AccountName)
+	assert.Equal(t, "fio-csv-2024", bank.Preset)
+	assert.Equal(t, ColumnName{"Objem"}, bank.ColumnNames.AmountAccount)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"strconv"
+
+	"golang.org/x/exp/slices"
+)
+
+// Group the payees listed in payeeIsTravel belong to
+const TravelGroup = "travel"
+
+// Named group of payees sharing settings.  Payees join groups with
+// their `groups` list:
+//
+//	groups:
+//	  subscriptions:
+//	    accountTemplate: 'Expenses:Subscriptions:{{ .Payee.Name }}'
+//	    tags: [recurring]
+//	    notes:
+//	      - text: still using it?
+//	payees:
+//	  Netflix:
+//	    payeeRaw: '^netflix'
+//	    groups: [subscriptions, kids]
+//
+// When a payee belongs to several groups, the groups listed later
+// override the earlier ones, and the payee's own settings override all
+// of them.  Groups do not need to be defined to be used in matchers,
+// notes and templates.
+type Group struct {
+	// Meta added to the transactions of the group's payees
+	Meta *map[string]string `yaml:"meta"`
+
+	// Account template of the payees without an account
+	AccountTemplate string `yaml:"accountTemplate"`
+
+	// Notes of the group's payees.  The group conditions of the notes
+	// are replaced by the group.
+	Notes []NoteRule `yaml:"notes"`
+
+	// Ledger tags added to the transactions of the group's payees
+	Tags []string `yaml:"tags"`
+
+	// Where the group is defined
+	Source Source `yaml:"-"`
+}
+
+// Report whether the payee belongs to the group
+func (p *Payee) InGroup(group string) bool {
+	return slices.Contains(p.Groups, group)
+}
+
+func appendMissing(values []string, added ...string) []string {
+	for _, value := range added {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Apply the settings of the groups to their payees and add the group
+// notes to Notes
+func (c *Config) resolveGroups() {
+	for _, name := range c.PayeeIsTravel {
+		if payee, exists := c.Payees[name]; exists {
+			payee.Groups = appendMissing(payee.Groups, TravelGroup)
+		}
+	}
+
+	for name, group := range c.Groups {
+		group.Source = c.source("groups", name)
+	}
+
+	for _, name := range sortedGroupNames(c.Groups) {
+		for i, note := range c.Groups[name].Notes {
+			note.Group = Names{name}
+			note.Source = c.source("groups", name, "notes", strconv.Itoa(i))
+			c.Notes = append(c.Notes, note)
+		}
+	}
+
+	for _, payee := range c.Payees {
+		meta := make(map[string]string)
+		var tags []string
+		accountTemplate := ""
+
+		for _, name := range payee.Groups {
+			group, exists := c.Groups[name]
+			if !exists {
+				continue
+			}
+
+			if group.Meta != nil {
+				for key, value := range *group.Meta {
+					meta[key] = value
+				}
+			}
+			if group.AccountTemplate != "" {
+				accountTemplate = group.AccountTemplate
+			}
+			tags = appendMissing(tags, group.Tags...)
+		}
+
+		if payee.Meta != nil {
+			for key, value := range *payee.Meta {
+				meta[key] = value
+			}
+		}
+		if len(meta) > 0 {
+			payee.Meta = &meta
+		}
+
+		if payee.Account == "" && payee.AccountTemplate == "" {
+			payee.AccountTemplate = accountTemplate
+		}
+
+		payee.Tags = appendMissing(tags, payee.Tags...)
+	}
+}
+
+func sortedGroupNames(groups map[string]*Group) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig_groups(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, `
+groups:
+  subscriptions:
+    accountTemplate: 'Expenses:Subscriptions:{{ .Payee.Name }}'
+    meta: {Recurring: monthly, Owner: me}
+    tags: [recurring]
+    notes:
+      - text: still using it?
+  kids:
+    meta: {Owner: kids}
+    tags: [kids, recurring]
+payees:
+  Netflix:
+    payeeRaw: '^netflix'
+    groups: [subscriptions, kids]
+  Spotify:
+    payeeRaw: '^spotify'
+    groups: subscriptions
+    meta: {Recurring: yearly}
+    tags: [music]
+  RegioJet: '^regiojet'
+accounts:
+  Expenses:
+    Music: Spotify
+    Travel: RegioJet
+payeeIsTravel: [RegioJet]
+notes:
+  - group: travel
+    text: add to/from/location
+`))
+	assert.Nil(t, err)
+
+	netflix := config.Payees["Netflix"]
+	assert.Equal(t, "Expenses:Subscriptions:{{ .Payee.Name }}", netflix.AccountTemplate)
+	assert.Equal(t, map[string]string{"Recurring": "monthly", "Owner": "kids"}, *netflix.Meta)
+	assert.Equal(t, []string{"recurring", "kids"}, netflix.Tags)
+	assert.True(t, netflix.InGroup("kids"))
+
+	// the payee's own account and meta win
+	spotify := config.Payees["Spotify"]
+	assert.Equal(t, "Expenses:Music", spotify.Account)
+	assert.Equal(t, "", spotify.AccountTemplate)
+	assert.Equal(t, map[string]string{"Recurring": "yearly", "Owner": "me"}, *spotify.Meta)
+	assert.Equal(t, []string{"recurring", "music"}, spotify.Tags)
+
+	assert.True(t, config.Payees["RegioJet"].InGroup(TravelGroup))
+
+	assert.Equal(t, 2, len(config.Notes))
+	assert.Equal(t, Names{"subscriptions"}, config.Notes[1].Group)
+	assert.Equal(t, "still using it?", config.Notes[1].Text)
+	assert.Equal(t, 8, config.Notes[1].Source.Line)
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position of a definition in the config files
+type Source struct {
+	File string
+	Line int
+}
+
+func (s Source) String() string {
+	if s.File == "" {
+		return "built-in"
+	}
+
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Config files read so far.  The config can be split into several
+// files with `include`, a list of file names or globs relative to the
+// including file:
+//
+//	include:
+//	  - payees/*.yaml
+//	  - banks/*.yaml
+//
+// The included files are merged into the including one: mappings are
+// merged recursively and lists are concatenated.  Payees and banks
+// must be defined only once.
+type configFiles struct {
+	// File each node was read from
+	files map[*yaml.Node]string
+
+	// Files being read, to detect include cycles
+	reading map[string]bool
+
+	// All files read, in order
+	fileNames []string
+}
+
+func newConfigFiles() *configFiles {
+	return &configFiles{
+		files:   make(map[*yaml.Node]string),
+		reading: make(map[string]bool),
+	}
+}
+
+func (cf *configFiles) markNodes(node *yaml.Node, fileName string) {
+	cf.files[node] = fileName
+	for _, child := range node.Content {
+		cf.markNodes(child, fileName)
+	}
+}
+
+func (cf *configFiles) source(node *yaml.Node) Source {
+	file, exists := cf.files[node]
+	if !exists {
+		return Source{}
+	}
+
+	return Source{File: file, Line: node.Line}
+}
+
+// Read the config file and the files it includes, return the merged
+// root mapping
+func (cf *configFiles) read(fileName string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if cf.reading[abs] {
+		return nil, fmt.Errorf("%s is included recursively", fileName)
+	}
+	cf.reading[abs] = true
+	defer delete(cf.reading, abs)
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	cf.fileNames = append(cf.fileNames, fileName)
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: the config must be a mapping", fileName)
+	}
+	cf.markNodes(root, fileName)
+
+	if err := cf.validate(root); err != nil {
+		return nil, err
+	}
+
+	include, _ := mappingEntry(root, "include")
+	if include == nil {
+		return root, nil
+	}
+	removeMappingEntry(root, "include")
+
+	var patterns []*yaml.Node
+	switch include.Kind {
+	case yaml.ScalarNode:
+		patterns = []*yaml.Node{include}
+	case yaml.SequenceNode:
+		patterns = include.Content
+	default:
+		return nil, fmt.Errorf("%s: include must be a file name or a list of file names", cf.source(include))
+	}
+
+	for _, pattern := range patterns {
+		path := pattern.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(fileName), path)
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid include pattern `%s': %s", cf.source(pattern), pattern.Value, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern.Value, "*?[") {
+			return nil, fmt.Errorf("%s: included file %s does not exist", cf.source(pattern), path)
+		}
+
+		for _, match := range matches {
+			included, err := cf.read(match)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := cf.merge(root, included, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// Merge the src mapping into dst.  Mappings are merged recursively,
+// lists are concatenated.  Keys of payees and banks, and keys with
+// any other values, must not be defined twice.
+func (cf *configFiles) merge(dst *yaml.Node, src *yaml.Node, path string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], resolveAlias(src.Content[i+1])
+
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+
+		existing, index := mappingEntry(dst, key.Value)
+		if index == -1 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+		existing = resolveAlias(existing)
+
+		unique := path == "payees" || path == "banks"
+		switch {
+		case !unique && isNull(existing):
+			dst.Content[index+1] = value
+		case !unique && isNull(value):
+		case !unique && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if err := cf.merge(existing, value, keyPath); err != nil {
+				return err
+			}
+		case !unique && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			existing.Content = append(existing.Content, value.Content...)
+		default:
+			return fmt.Errorf("%s is defined twice, in %s and %s", keyPath, cf.source(dst.Content[index]), cf.source(key))
+		}
+	}
+
+	return nil
+}
+
+// Set the pattern sources from the node of a payee's patterns, which
+// is a single pattern or a list of them
+func (cf *configFiles) setPatternSources(patterns PayeePatterns, node *yaml.Node) {
+	node = resolveAlias(node)
+
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+
+	for i := range patterns {
+		if i < len(items) {
+			patterns[i].Source = cf.source(items[i])
+		}
+	}
+}
+
+// Set the sources of the payees, their patterns and the banks
+func (cf *configFiles) setSources(root *yaml.Node, config *Config) {
+	if banks := resolveAlias(mappingValue(root, "banks")); banks != nil {
+		for i := 0; i+1 < len(banks.Content); i += 2 {
+			if bank, exists := config.Banks[banks.Content[i].Value]; exists {
+				bank.Source = cf.source(banks.Content[i])
+			}
+		}
+	}
+
+	if payees := resolveAlias(mappingValue(root, "payees")); payees != nil {
+		for i := 0; i+1 < len(payees.Content); i += 2 {
+			payee, exists := config.Payees[payees.Content[i].Value]
+			if !exists {
+				continue
+			}
+
+			payee.Source = cf.source(payees.Content[i])
+
+			value := resolveAlias(payees.Content[i+1])
+			if value.Kind != yaml.MappingNode {
+				cf.setPatternSources(payee.PayeeRaw, value)
+				continue
+			}
+
+			for field, patterns := range payee.patternFields() {
+				if node := mappingValue(value, field); node != nil {
+					cf.setPatternSources(patterns, node)
+				}
+			}
+
+			if custom := resolveAlias(mappingValue(value, "custom")); custom != nil {
+				for field, patterns := range payee.Custom {
+					if node := mappingValue(custom, field); node != nil {
+						cf.setPatternSources(patterns, node)
+					}
+				}
+			}
+		}
+	}
+
+	// payees defined implicitly in the accounts hierarchy
+	if accounts := resolveAlias(mappingValue(root, "accounts")); accounts != nil {
+		cf.setAccountsSources(accounts, config.Payees)
+	}
+}
+
+func (cf *configFiles) setImplicitSource(node *yaml.Node, payees map[string]*Payee) {
+	payee, exists := payees[node.Value]
+	if !exists || payee.Source.File != "" {
+		return
+	}
+
+	payee.Source = cf.source(node)
+	for i := range payee.PayeeRaw {
+		payee.PayeeRaw[i].Source = payee.Source
+	}
+}
+
+func (cf *configFiles) setAccountsSources(node *yaml.Node, payees map[string]*Payee) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+
+		switch {
+		case isNull(value):
+			cf.setImplicitSource(key, payees)
+		case value.Kind == yaml.ScalarNode:
+			cf.setImplicitSource(value, payees)
+		case value.Kind == yaml.SequenceNode:
+			for _, item := range value.Content {
+				cf.setImplicitSource(item, payees)
+			}
+		case value.Kind == yaml.MappingNode:
+			cf.setAccountsSources(value, payees)
+		}
+	}
+}
+
+// Record the source of every mapping key and sequence item by its
+// dotted path, e.g. `banks.fio.twinTransactions.0`
+func (cf *configFiles) recordPaths(node *yaml.Node, path string, sources map[string]Source) {
+	node = resolveAlias(node)
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyPath := join(node.Content[i].Value)
+			sources[keyPath] = cf.source(node.Content[i])
+			cf.recordPaths(node.Content[i+1], keyPath, sources)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := join(strconv.Itoa(i))
+			sources[itemPath] = cf.source(item)
+			cf.recordPaths(item, itemPath, sources)
+		}
+	}
+}
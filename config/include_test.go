@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		fileName := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return filepath.Join(dir, "config.yaml")
+}
+
+func TestReadConfig_include(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include:
+  - payees/*.yaml
+  - banks.yaml
+accounts:
+  Assets:
+    Bank: MyBank
+payeeIsTravel: [Hotel]
+`,
+		"payees/shops.yaml": `payees:
+  Tesco:
+    payeeRaw:
+      - '^tesco'
+      - '^tesco stores'
+accounts:
+  Expenses:
+    Groceries: [Tesco, Albert]
+payeeIsTravel: [RegioJet]
+`,
+		"payees/bank.yaml": `payees:
+  MyBank: '^mybank$'
+`,
+		"banks.yaml": `banks:
+  mybank:
+    accountName: Assets:Bank
+    datePatternFrom: 02.01.2006
+`,
+	})
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+
+	dir := filepath.Dir(fileName)
+
+	tesco := config.Payees["Tesco"]
+	assert.Equal(t, "Expenses:Groceries", tesco.Account)
+	assert.Equal(t, Source{File: filepath.Join(dir, "payees/shops.yaml"), Line: 2}, tesco.Source)
+	assert.Equal(t, Source{File: filepath.Join(dir, "payees/shops.yaml"), Line: 5}, tesco.PayeeRaw[1].Source)
+
+	// implicit payee from the accounts hierarchy
+	albert := config.Payees["Albert"]
+	assert.Equal(t, Source{File: filepath.Join(dir, "payees/shops.yaml"), Line: 8}, albert.Source)
+
+	assert.Equal(t, "Assets:Bank", config.Payees["MyBank"].Account)
+	assert.Equal(t, Source{File: filepath.Join(dir, "banks.yaml"), Line: 2}, config.Banks["mybank"].Source)
+	assert.Equal(t, []string{"Hotel", "RegioJet"}, config.PayeeIsTravel)
+
+	assert.Equal(t, []string{
+		fileName,
+		filepath.Join(dir, "payees/bank.yaml"),
+		filepath.Join(dir, "payees/shops.yaml"),
+		filepath.Join(dir, "banks.yaml"),
+	}, config.Files())
+}
+
+func TestReadConfig_include_duplicate_payee(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: [a.yaml, b.yaml]
+`,
+		"a.yaml": `payees:
+  Tesco: '^tesco'
+`,
+		"b.yaml": `payees:
+
+  Tesco: '^tesco praha'
+`,
+	})
+
+	_, err := ReadConfig(fileName)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "payees.Tesco is defined twice"), err.Error())
+	assert.True(t, strings.Contains(err.Error(), "a.yaml:2"), err.Error())
+	assert.True(t, strings.Contains(err.Error(), "b.yaml:3"), err.Error())
+}
+
+func TestReadConfig_include_duplicate_bank(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: banks/*.yaml
+banks:
+  fio:
+    datePatternFrom: 02.01.2006
+`,
+		"banks/fio.yaml": `banks:
+  fio:
+    datePatternFrom: 2006-01-02
+`,
+	})
+
+	_, err := ReadConfig(fileName)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "banks.fio is defined twice"), err.Error())
+}
+
+func TestReadConfig_include_missing_file(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: [missing.yaml, optional/*.yaml]
+`,
+	})
+
+	_, err := ReadConfig(fileName)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "missing.yaml does not exist"), err.Error())
+}
+
+func TestReadConfig_include_cycle(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: a.yaml
+`,
+		"a.yaml": `include: config.yaml
+`,
+	})
+
+	_, err := ReadConfig(fileName)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "included recursively"), err.Error())
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Legacy mapping sections, folded into Payees when the config is
+// read:
+//
+//	toPayeeRaw:
+//	  pattern:                 # pattern -> raw payee
+//	    '^TESCO.*': TESCO
+//	toPayee:
+//	  payeeRaw:                # raw payee -> payee
+//	    TESCO: Tesco
+//	  paymentType:             # payment type -> payee
+//	    Cash withdrawal: ATM
+//	  receiverAccountNumber:   # account number -> payee
+//	    2000145399/2010: Landlord
+//	toAccountTo:
+//	  payee:                   # payee -> account
+//	    Tesco: Expenses:Groceries
+//
+// A raw payee of toPayeeRaw which is not in toPayee.payeeRaw is used
+// as the payee name directly.  Raw payees are matched exactly, but
+// case-insensitively like the other payee patterns.
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Get the payee of the legacy mapping, creating it if it does not
+// exist
+func (c *Config) legacyPayee(name string, source Source) *Payee {
+	payee, exists := c.Payees[name]
+	if !exists {
+		payee = &Payee{Name: name, Source: source}
+		c.Payees[name] = payee
+	}
+
+	return payee
+}
+
+// Fold the legacy toPayeeRaw, toPayee and toAccountTo sections into
+// Payees
+func (c *Config) foldLegacy() {
+	for _, raw := range sortedKeys(c.ToPayee.PayeeRaw) {
+		source := c.source("toPayee", "payeeRaw", raw)
+		payee := c.legacyPayee(c.ToPayee.PayeeRaw[raw], source)
+		payee.PayeeRaw = append(payee.PayeeRaw, PayeePattern{Value: "^" + regexp.QuoteMeta(raw) + "$", Source: source})
+	}
+
+	for _, pattern := range sortedKeys(c.ToPayeeRaw.Pattern) {
+		source := c.source("toPayeeRaw", "pattern", pattern)
+		name := c.ToPayeeRaw.Pattern[pattern]
+		if mapped, exists := c.ToPayee.PayeeRaw[name]; exists {
+			name = mapped
+		}
+		payee := c.legacyPayee(name, source)
+		payee.PayeeRaw = append(payee.PayeeRaw, PayeePattern{Value: pattern, Source: source})
+	}
+
+	for _, paymentType := range sortedKeys(c.ToPayee.PaymentType) {
+		source := c.source("toPayee", "paymentType", paymentType)
+		payee := c.legacyPayee(c.ToPayee.PaymentType[paymentType], source)
+		payee.PaymentType = append(payee.PaymentType, PayeePattern{Value: paymentType, Source: source})
+	}
+
+	for _, number := range sortedKeys(c.ToPayee.ReceiverAccountNumber) {
+		source := c.source("toPayee", "receiverAccountNumber", number)
+		payee := c.legacyPayee(c.ToPayee.ReceiverAccountNumber[number], source)
+		payee.ReceiverAccountNumber = append(payee.ReceiverAccountNumber, PayeePattern{Value: number, Source: source})
+	}
+
+	for _, name := range sortedKeys(c.ToAccountTo.Payee) {
+		assignAccount(name, c.ToAccountTo.Payee[name], c.Payees)
+		if payee := c.Payees[name]; payee.Source.File == "" {
+			payee.Source = c.source("toAccountTo", "payee", name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig_legacy_sections(t *testing.T) {
+	fileName := writeConfig(t, `
+payees:
+  Landlord:
+    payeeRaw: '^landlord'
+toPayeeRaw:
+  pattern:
+    '^TESCO.*': TESCO
+    '^SHELL \d+': Shell
+toPayee:
+  payeeRaw:
+    TESCO: Tesco
+    ALBERT (CZ): Albert
+  paymentType:
+    Cash withdrawal: ATM
+  receiverAccountNumber:
+    2000145399/2010: Landlord
+toAccountTo:
+  payee:
+    Tesco: Expenses:Groceries
+    Albert: Expenses:Groceries
+    Shell: Expenses:Car
+    ATM: Assets:Cash
+`)
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+
+	tesco := config.Payees["Tesco"]
+	assert.Equal(t, "Expenses:Groceries", tesco.Account)
+	assert.Equal(t, []string{`^TESCO$`, `^TESCO.*`}, patternValues(tesco.PayeeRaw))
+	assert.Equal(t, Source{File: fileName, Line: 11}, tesco.Source)
+	assert.Equal(t, Source{File: fileName, Line: 7}, tesco.PayeeRaw[1].Source)
+
+	assert.Equal(t, []string{`^ALBERT \(CZ\)$`}, patternValues(config.Payees["Albert"].PayeeRaw))
+
+	// raw payee not in toPayee.payeeRaw is the payee name
+	shell := config.Payees["Shell"]
+	assert.Equal(t, "Expenses:Car", shell.Account)
+	assert.Equal(t, []string{`^SHELL \d+`}, patternValues(shell.PayeeRaw))
+
+	atm := config.Payees["ATM"]
+	assert.Equal(t, "Assets:Cash", atm.Account)
+	assert.Equal(t, []string{"Cash withdrawal"}, patternValues(atm.PaymentType))
+
+	landlord := config.Payees["Landlord"]
+	assert.Equal(t, []string{"^landlord"}, patternValues(landlord.PayeeRaw))
+	assert.Equal(t, []string{"2000145399/2010"}, patternValues(landlord.ReceiverAccountNumber))
+	assert.Equal(t, Source{File: fileName, Line: 3}, landlord.Source)
+}
+
+func patternValues(patterns PayeePatterns) []string {
+	var values []string
+	for _, pattern := range patterns {
+		values = append(values, pattern.Value)
+	}
+
+	return values
+}
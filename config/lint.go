@@ -0,0 +1,577 @@
+package config
+
+import (
+	"bank-to-ledger/bankaccount"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Problem found in the config by Lint
+type Diagnostic struct {
+	Source  Source
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Source, d.Message)
+}
+
+type linter struct {
+	config      Config
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(source Source, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Source: source, Message: fmt.Sprintf(format, args...)})
+}
+
+// Source of the key at the dotted path, see configFiles.recordPaths
+func (c Config) source(path ...string) Source {
+	return c.sources[strings.Join(path, ".")]
+}
+
+// Check the config for problems which do not prevent it from loading
+// but make the conversion fail or behave unexpectedly.  The problems
+// are sorted by their position in the config files.
+func (c Config) Lint() []Diagnostic {
+	l := &linter{config: c}
+
+	l.checkPayees()
+	l.checkOverlaps()
+	l.checkAccounts()
+	l.checkBanks()
+	l.checkNotes()
+	l.checkCurrencies()
+
+	sortDiagnostics(l.diagnostics)
+	return l.diagnostics
+}
+
+// Report currency mappings not used by any of the currencies, e.g.
+// the currencies of the transactions in the converted statements
+func (c Config) LintCurrencies(used map[string]bool) []Diagnostic {
+	l := &linter{config: c}
+
+	for currency := range c.Currencies.SymbolMap {
+		if !used[currency] {
+			l.report(c.source("currencies", "symbolMap", currency), "currency mapping of %s is not used by any transaction", currency)
+		}
+	}
+
+	sortDiagnostics(l.diagnostics)
+	return l.diagnostics
+}
+
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Source.File != b.Source.File {
+			return a.Source.File < b.Source.File
+		}
+		if a.Source.Line != b.Source.Line {
+			return a.Source.Line < b.Source.Line
+		}
+		return a.Message < b.Message
+	})
+}
+
+func (c Config) payeeNames() []string {
+	names := make([]string, 0, len(c.Payees))
+	for name := range c.Payees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func patternSource(payee *Payee, pattern PayeePattern) Source {
+	if pattern.Source.File != "" {
+		return pattern.Source
+	}
+
+	return payee.Source
+}
+
+// Pattern fields of the payee matched as regular expressions,
+// including the custom fields
+func (p *Payee) regexpFields() map[string]PayeePatterns {
+	fields := map[string]PayeePatterns{
+		"payeeRaw":  p.PayeeRaw,
+		"noteForMe": p.NoteForMe,
+	}
+	for field, patterns := range p.Custom {
+		fields["custom."+field] = patterns
+	}
+
+	return fields
+}
+
+func (l *linter) checkTemplate(source Source, description string, text string) {
+	if _, err := template.New("lint").Parse(text); err != nil {
+		l.report(source, "%s is not a valid template: %s", description, err)
+	}
+}
+
+func (l *linter) checkMetaTemplates(source Source, description string, meta *map[string]string) {
+	if meta == nil {
+		return
+	}
+
+	for key, value := range *meta {
+		l.checkTemplate(source, fmt.Sprintf("%s meta %s", description, key), value)
+	}
+}
+
+func (l *linter) checkPayees() {
+	for _, name := range l.config.payeeNames() {
+		payee := l.config.Payees[name]
+
+		if !payee.HasAccount() {
+			l.report(payee.Source, "payee `%s' has no assigned account", name)
+		}
+
+		l.checkDateRange(payee.Source, fmt.Sprintf("payee `%s'", name), payee.DateRange)
+		l.checkAccountRules(payee)
+
+		l.checkTemplate(payee.Source, fmt.Sprintf("template of payee `%s'", name), payee.Template)
+		l.checkTemplate(payee.Source, fmt.Sprintf("account template of payee `%s'", name), payee.AccountTemplate)
+		l.checkMetaTemplates(payee.Source, fmt.Sprintf("payee `%s'", name), payee.Meta)
+
+		for _, patterns := range payee.patternFields() {
+			for _, pattern := range patterns {
+				l.checkMetaTemplates(patternSource(payee, pattern), fmt.Sprintf("pattern `%s' of payee `%s'", pattern.Value, name), pattern.Meta)
+				l.checkDateRange(patternSource(payee, pattern), fmt.Sprintf("pattern `%s' of payee `%s'", pattern.Value, name), pattern.DateRange)
+			}
+		}
+
+		for field, patterns := range payee.regexpFields() {
+			for _, pattern := range patterns {
+				re, err := parsePattern(pattern.Value)
+				if err != nil {
+					// report the error without the case-insensitive flag
+					if _, rawErr := parseRegexp(pattern.Value); rawErr != nil {
+						err = rawErr
+					}
+					l.report(patternSource(payee, pattern), "%s pattern `%s' of payee `%s' is not a valid regular expression: %s", field, pattern.Value, name, err)
+				} else if neverMatches(re) {
+					l.report(patternSource(payee, pattern), "%s pattern `%s' of payee `%s' can never match", field, pattern.Value, name)
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) checkDateRange(source Source, description string, r DateRange) {
+	if r.ValidFrom != nil && r.ValidTo != nil && r.ValidFrom.After(r.ValidTo.Time) {
+		l.report(source, "%s is valid from %s, after its validTo %s", description, r.ValidFrom, r.ValidTo)
+	}
+}
+
+// The first matching account rule is used, so a rule covered by an
+// earlier one is never used
+func (l *linter) checkAccountRules(payee *Payee) {
+	for i, rule := range payee.Accounts {
+		description := fmt.Sprintf("account rule %d of payee `%s'", i, payee.Name)
+
+		if rule.Account == "" && rule.AccountTemplate == "" {
+			l.report(rule.Source, "%s has no account", description)
+		}
+		l.checkTemplate(rule.Source, "account template of "+description, rule.AccountTemplate)
+		l.checkDateRange(rule.Source, description, rule.DateRange)
+		l.checkAmountCondition(rule.Source, description, rule.Amount)
+
+		for j, earlier := range payee.Accounts[:i] {
+			if earlier.Covers(rule) {
+				l.report(rule.Source, "%s (%s) is never used, rule %d (%s) matches all its transactions",
+					description, rule.Conditions(), j, earlier.Conditions())
+				break
+			}
+		}
+	}
+
+	if payee.Account == "" && payee.AccountTemplate == "" {
+		if payee.IncomingAccount != "" && payee.OutgoingAccount == "" {
+			l.report(payee.Source, "payee `%s' has no account for outgoing payments", payee.Name)
+		}
+		if payee.OutgoingAccount != "" && payee.IncomingAccount == "" {
+			l.report(payee.Source, "payee `%s' has no account for incoming payments", payee.Name)
+		}
+	}
+}
+
+func (l *linter) checkAmountCondition(source Source, description string, c AmountCondition) {
+	if c.MinAmount != nil && c.MaxAmount != nil && *c.MinAmount > *c.MaxAmount {
+		l.report(source, "%s has minAmount %g greater than its maxAmount %g", description, *c.MinAmount, *c.MaxAmount)
+	}
+}
+
+type patternEntry struct {
+	payee    *Payee
+	pattern  PayeePattern
+	regexp   *regexp.Regexp
+	examples []string
+}
+
+// Report whether two patterns of an exactly compared field match the
+// same values
+type sameValue func(a string, b string) bool
+
+var exactFields = map[string]sameValue{
+	"receiverAccountNumber": bankaccount.Equal,
+	"paymentType":           func(a string, b string) bool { return a == b },
+	"variableSymbol":        sameSymbol,
+	"constantSymbol":        sameSymbol,
+	"specificSymbol":        sameSymbol,
+}
+
+func sameSymbol(a string, b string) bool {
+	return NormalizeSymbol(a) == NormalizeSymbol(b)
+}
+
+// The first example of a matched by b, empty if none
+func overlapExample(a patternEntry, b patternEntry) (string, bool) {
+	for _, example := range a.examples {
+		if a.regexp.MatchString(example) && b.regexp.MatchString(example) {
+			return example, true
+		}
+	}
+
+	return "", false
+}
+
+// Report whether the patterns can match transactions of the same date
+func (a patternEntry) sameDates(b patternEntry) bool {
+	return a.payee.DateRange.Overlaps(b.payee.DateRange) && a.pattern.DateRange.Overlaps(b.pattern.DateRange)
+}
+
+// Payees are tried in no particular order, so a transaction matched by
+// patterns of two payees is categorized randomly
+func (l *linter) checkOverlaps() {
+	regexpEntries := make(map[string][]patternEntry)
+	exactEntries := make(map[string][]patternEntry)
+
+	for _, name := range l.config.payeeNames() {
+		payee := l.config.Payees[name]
+
+		for field, patterns := range payee.regexpFields() {
+			for _, pattern := range patterns {
+				parsed, err := parsePattern(pattern.Value)
+				if err != nil {
+					continue
+				}
+				regexpEntries[field] = append(regexpEntries[field], patternEntry{
+					payee:    payee,
+					pattern:  pattern,
+					regexp:   regexp.MustCompile("(?i)" + pattern.Value),
+					examples: examples(parsed),
+				})
+			}
+		}
+
+		for field := range exactFields {
+			for _, pattern := range payee.patternFields()[field] {
+				exactEntries[field] = append(exactEntries[field], patternEntry{payee: payee, pattern: pattern})
+			}
+		}
+	}
+
+	for field, entries := range regexpEntries {
+		reported := make(map[[2]string]bool)
+		for i, a := range entries {
+			for _, b := range entries[i+1:] {
+				pair := [2]string{a.payee.Name, b.payee.Name}
+				if a.payee == b.payee || reported[pair] || !a.sameDates(b) {
+					continue
+				}
+
+				example, overlaps := overlapExample(a, b)
+				if !overlaps {
+					example, overlaps = overlapExample(b, a)
+				}
+				if overlaps {
+					reported[pair] = true
+					l.report(patternSource(b.payee, b.pattern), "%s pattern `%s' of payee `%s' overlaps with `%s' of payee `%s' (%s), both match `%s'",
+						field, b.pattern.Value, b.payee.Name, a.pattern.Value, a.payee.Name, patternSource(a.payee, a.pattern), example)
+				}
+			}
+		}
+	}
+
+	for field, entries := range exactEntries {
+		for i, a := range entries {
+			for _, b := range entries[i+1:] {
+				if a.payee != b.payee && a.sameDates(b) && exactFields[field](a.pattern.Value, b.pattern.Value) {
+					l.report(patternSource(b.payee, b.pattern), "%s `%s' of payee `%s' is also used by payee `%s' (%s)",
+						field, b.pattern.Value, b.payee.Name, a.payee.Name, patternSource(a.payee, a.pattern))
+				}
+			}
+		}
+	}
+}
+
+type accountAssignment struct {
+	account string
+	source  Source
+}
+
+// Collect the payees assigned to accounts in the accounts hierarchy,
+// following the rules of MapPayees
+func (l *linter) collectAssignments(account Account, path string, keyPath string, assignments map[string][]accountAssignment) {
+	for key, value := range account {
+		accountName := key
+		if path != "" {
+			accountName = path + ":" + key
+		}
+		if key == "self" {
+			accountName = path
+		}
+
+		valuePath := keyPath + "." + key
+		assign := func(payee string, source Source) {
+			assignments[payee] = append(assignments[payee], accountAssignment{accountName, source})
+		}
+
+		switch value := value.(type) {
+		case nil:
+			assign(key, l.config.source(valuePath))
+		case Account:
+			l.collectAssignments(value, accountName, valuePath, assignments)
+		case string:
+			assign(value, l.config.source(valuePath))
+		case []interface{}:
+			for i, item := range value {
+				if payee, ok := item.(string); ok {
+					assign(payee, l.config.source(valuePath, strconv.Itoa(i)))
+				}
+			}
+		}
+	}
+}
+
+// Payees assigned to more than one account get one of them at random
+func (l *linter) checkAccounts() {
+	assignments := make(map[string][]accountAssignment)
+
+	for name := range l.config.Payees {
+		if source, exists := l.config.sources["payees."+name+".account"]; exists {
+			assignments[name] = append(assignments[name], accountAssignment{l.config.Payees[name].Account, source})
+		}
+	}
+
+	for name, bank := range l.config.Banks {
+		payee, exists := l.config.Payees[bank.PayeeName]
+		if exists && len(assignments[payee.Name]) == 0 {
+			assignments[payee.Name] = append(assignments[payee.Name], accountAssignment{bank.AccountName, l.config.source("banks", name, "payee")})
+		}
+	}
+
+	l.collectAssignments(l.config.Accounts, "", "accounts", assignments)
+
+	for payee, assigned := range assignments {
+		accounts := make(map[string]bool)
+		for _, a := range assigned {
+			accounts[a.account] = true
+		}
+		if len(accounts) < 2 {
+			continue
+		}
+
+		sort.Slice(assigned, func(i, j int) bool {
+			return assigned[i].source.String() < assigned[j].source.String()
+		})
+
+		var descriptions []string
+		for _, a := range assigned {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", a.account, a.source))
+		}
+		l.report(assigned[len(assigned)-1].source, "payee `%s' is assigned to more than one account: %s", payee, strings.Join(descriptions, ", "))
+	}
+}
+
+// Fields of the matcher compared by Transaction.Match
+func matcherIsEmpty(m Matcher) bool {
+	compared := Matcher{
+		Payee:                 m.Payee,
+		PayeeRaw:              m.PayeeRaw,
+		PaymentType:           m.PaymentType,
+		ReceiverAccountNumber: m.ReceiverAccountNumber,
+		NoteForMe:             m.NoteForMe,
+		NoteForReceiver:       m.NoteForReceiver,
+		VariableSymbol:        m.VariableSymbol,
+		ConstantSymbol:        m.ConstantSymbol,
+		SpecificSymbol:        m.SpecificSymbol,
+		Group:                 m.Group,
+	}
+
+	return len(m.Custom) == 0 && reflect.DeepEqual(compared, Matcher{})
+}
+
+func (l *linter) checkMatchers(matchers []Matcher, path string, description string) {
+	for i, m := range matchers {
+		source := l.config.source(path, strconv.Itoa(i))
+		if matcherIsEmpty(m) {
+			l.report(source, "%s matcher has no compared fields, it matches every transaction", description)
+		}
+		if _, exists := l.config.Payees[m.Payee]; m.Payee != "" && !exists {
+			l.report(source, "%s matcher refers to undefined payee `%s'", description, m.Payee)
+		}
+		if m.Group != "" && !l.groupUsed(m.Group) {
+			l.report(source, "%s matcher refers to group `%s' no payee belongs to", description, m.Group)
+		}
+	}
+}
+
+func (l *linter) checkBanks() {
+	names := make([]string, 0, len(l.config.Banks))
+	for name := range l.config.Banks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	l.checkBankDetection(names)
+
+	for _, name := range names {
+		bank := l.config.Banks[name]
+		path := "banks." + name
+
+		if bank.PayeeName != "" && bank.Payee == nil {
+			l.report(l.config.source(path, "payee"), "bank `%s' refers to undefined payee `%s'", name, bank.PayeeName)
+		}
+
+		if bank.FileNamePattern != "" {
+			if _, err := regexp.Compile(bank.FileNamePattern); err != nil {
+				l.report(l.config.source(path, "fileNamePattern"), "fileNamePattern of bank `%s' is not a valid regular expression: %s", name, err)
+			}
+		}
+
+		for i, df := range bank.DerivedFields {
+			source := l.config.source(path, "derivedFields", strconv.Itoa(i))
+			if re, err := regexp.Compile(df.Pattern); err != nil {
+				l.report(source, "derived field pattern of bank `%s' is not a valid regular expression: %s", name, err)
+			} else if parsed, _ := parseRegexp(df.Pattern); parsed != nil && neverMatches(parsed) {
+				l.report(source, "derived field pattern `%s' of bank `%s' can never match", re, name)
+			}
+			for _, field := range sortedKeys(df.Fields) {
+				if err := checkDerivedFieldTarget(field); err != nil {
+					l.report(source, "derived field of bank `%s' %s", name, err)
+				}
+			}
+		}
+
+		l.checkMetaTemplates(bank.Source, fmt.Sprintf("bank `%s'", name), bank.Meta)
+
+		for i, tt := range bank.TwinTransactions {
+			twinPath := fmt.Sprintf("%s.twinTransactions.%d", path, i)
+			source := l.config.source(twinPath)
+			description := fmt.Sprintf("twin transaction of bank `%s'", name)
+
+			if tt.Type != "sum" && tt.Type != "merge" {
+				l.report(source, "%s has unknown type `%s', expected sum or merge", description, tt.Type)
+			}
+			if len(tt.Anchor) == 0 {
+				l.report(source, "%s has no anchor matchers, it never applies", description)
+			}
+			if len(tt.Matchers) == 0 {
+				l.report(source, "%s has no matchers, the anchor is never paired", description)
+			}
+
+			l.checkMatchers(tt.Anchor, twinPath+".anchor", description+" anchor")
+			l.checkMatchers(tt.Matchers, twinPath+".matchers", description)
+		}
+
+		for i, ignored := range bank.IgnoredTransactions {
+			ignoredPath := fmt.Sprintf("%s.ignoredTransactions.%d.matchers", path, i)
+			l.checkMatchers(ignored.Matchers, ignoredPath, fmt.Sprintf("ignored transaction of bank `%s'", name))
+		}
+	}
+}
+
+// Report banks matching the same files, e.g. a bank inheriting the
+// fileNamePattern or identifyingColumns of the bank it extends.  Which
+// of them a file is converted with cannot be determined.
+func (l *linter) checkBankDetection(names []string) {
+	for i, name := range names {
+		bank := l.config.Banks[name]
+
+		for _, otherName := range names[:i] {
+			other := l.config.Banks[otherName]
+
+			if bank.FileNamePattern != "" && bank.FileNamePattern == other.FileNamePattern {
+				l.report(bank.Source, "bank `%s' has the same fileNamePattern `%s' as bank `%s' (%s), the files it matches need --bank-name",
+					name, bank.FileNamePattern, otherName, other.Source)
+			}
+
+			// banks with a fileNamePattern are told apart by the file
+			// name first
+			if bank.FileNamePattern != "" && other.FileNamePattern != "" {
+				continue
+			}
+			if isColumnPrefix(bank.IdentifyingColumns, other.IdentifyingColumns) || isColumnPrefix(other.IdentifyingColumns, bank.IdentifyingColumns) {
+				l.report(bank.Source, "bank `%s' and bank `%s' (%s) match the same header by identifyingColumns, the files they match need --bank-name or a fileNamePattern",
+					name, otherName, other.Source)
+			}
+		}
+	}
+}
+
+// Report whether the identifying columns are the leading columns of
+// the other ones, so a header matching the other ones matches them too
+func isColumnPrefix(columns []string, other []string) bool {
+	if len(columns) == 0 || len(columns) > len(other) {
+		return false
+	}
+
+	for i, column := range columns {
+		if NormalizeColumnName(column) != NormalizeColumnName(other[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Report whether any payee belongs to the group
+func (l *linter) groupUsed(group string) bool {
+	for _, payee := range l.config.Payees {
+		if payee.InGroup(group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *linter) checkNotes() {
+	for _, note := range l.config.Notes {
+		for _, payee := range note.Payee {
+			if _, exists := l.config.Payees[payee]; !exists {
+				l.report(note.Source, "note refers to undefined payee `%s'", payee)
+			}
+		}
+		for _, group := range note.Group {
+			if !l.groupUsed(group) {
+				l.report(note.Source, "note refers to group `%s' no payee belongs to", group)
+			}
+		}
+		if note.Text == "" && note.As != NoteAsPending {
+			l.report(note.Source, "note has no text")
+		}
+		l.checkAmountCondition(note.Source, "note", note.Amount)
+	}
+}
+
+// Amounts in currencies mapped to an empty symbol are printed without
+// the currency
+func (l *linter) checkCurrencies() {
+	for currency, symbol := range l.config.Currencies.SymbolMap {
+		if symbol.To == "" {
+			l.report(l.config.source("currencies", "symbolMap", currency), "currency mapping of %s has no `to' symbol", currency)
+		}
+	}
+}
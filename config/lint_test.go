@@ -0,0 +1,188 @@
+package config
+
+import (
+	"path/filepath"
+	"regexp/syntax"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lintMessages(t *testing.T, content string) []string {
+	fileName := writeConfig(t, content)
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+
+	// relative to the config directory for readability
+	var messages []string
+	for _, diagnostic := range config.Lint() {
+		messages = append(messages, strings.ReplaceAll(diagnostic.String(), filepath.Dir(fileName)+string(filepath.Separator), ""))
+	}
+
+	return messages
+}
+
+func containsMessage(messages []string, part string) bool {
+	for _, message := range messages {
+		if strings.Contains(message, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestNeverMatches(t *testing.T) {
+	for pattern, expected := range map[string]bool{
+		`^tesco`:              false,
+		`tesco$`:              false,
+		`^$`:                  false,
+		`a|b^`:                false,
+		`(foo)?^bar`:          false,
+		`foo^bar`:             true,
+		`foo$bar`:             true,
+		`(a^|b$c)`:            true,
+		`x[^\x00-\x{10FFFF}]`: true,
+	} {
+		re, err := syntax.Parse(pattern, syntax.Perl)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, neverMatches(re.Simplify()), pattern)
+	}
+}
+
+func TestLint_valid_config(t *testing.T) {
+	messages := lintMessages(t, `
+payees:
+  Tesco: '^tesco'
+  Albert: '^albert'
+accounts:
+  Expenses:
+    Groceries: [Tesco, Albert]
+`)
+
+	assert.Equal(t, 0, len(messages), strings.Join(messages, "\n"))
+}
+
+func TestLint_patterns(t *testing.T) {
+	messages := lintMessages(t, `
+payees:
+  Broken:
+    payeeRaw: '^(abc'
+    account: Expenses:A
+  Never:
+    payeeRaw: ['^never', 'foo$bar']
+    account: Expenses:B
+`)
+
+	assert.True(t, containsMessage(messages, "config.yaml:4: payeeRaw pattern `^(abc' of payee `Broken' is not a valid regular expression: error parsing regexp: missing closing ): `^(abc`"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:7: payeeRaw pattern `foo$bar' of payee `Never' can never match"), strings.Join(messages, "\n"))
+	assert.Equal(t, 2, len(messages), strings.Join(messages, "\n"))
+}
+
+func TestLint_overlaps(t *testing.T) {
+	messages := lintMessages(t, `
+payees:
+  Tesco: '^tesco'
+  TescoStores: 'tesco stores'
+  Rent:
+    variableSymbol: '0042'
+  Parking:
+    variableSymbol: '42'
+accounts:
+  Expenses: [Tesco, TescoStores, Rent, Parking]
+`)
+
+	assert.True(t, containsMessage(messages, "config.yaml:4: payeeRaw pattern `tesco stores' of payee `TescoStores' overlaps with `^tesco' of payee `Tesco' (config.yaml:3)"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "variableSymbol `0042' of payee `Rent' is also used by payee `Parking'"), strings.Join(messages, "\n"))
+	assert.Equal(t, 2, len(messages), strings.Join(messages, "\n"))
+}
+
+func TestLint_accounts_assigned_twice(t *testing.T) {
+	messages := lintMessages(t, `
+payees:
+  Tesco: '^tesco'
+accounts:
+  Expenses:
+    Groceries: Tesco
+    Food: [Bakery, Tesco]
+`)
+
+	assert.Equal(t, []string{"config.yaml:7: payee `Tesco' is assigned to more than one account: Expenses:Groceries (config.yaml:6), Expenses:Food (config.yaml:7)"}, messages)
+}
+
+func TestLint_banks(t *testing.T) {
+	messages := lintMessages(t, `
+payees:
+  Tesco:
+    payeeRaw: '^tesco'
+    account: Expenses:Groceries
+    template: '{{ .Payee.Name'
+banks:
+  mybank:
+    payee: Ghost
+    datePatternFrom: 02.01.2006
+    twinTransactions:
+      - type: merge
+        anchor:
+          - dateRaw: 01.01.2024
+    ignoredTransactions:
+      - matchers:
+          - payee: Nobody
+currencies:
+  symbolMap:
+    USD: {inFront: true}
+toPayeeRaw:
+  pattern:
+    '^x': missing
+`)
+
+	assert.True(t, containsMessage(messages, "config.yaml:3: template of payee `Tesco' is not a valid template"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:9: bank `mybank' refers to undefined payee `Ghost'"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:12: twin transaction of bank `mybank' has no matchers"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:14: twin transaction of bank `mybank' anchor matcher has no compared fields, it matches every transaction"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:17: ignored transaction of bank `mybank' matcher refers to undefined payee `Nobody'"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:20: currency mapping of USD has no `to' symbol"), strings.Join(messages, "\n"))
+	assert.True(t, containsMessage(messages, "config.yaml:23: payee `missing' has no assigned account"), strings.Join(messages, "\n"))
+	assert.Equal(t, 7, len(messages), strings.Join(messages, "\n"))
+}
+
+func TestLintCurrencies(t *testing.T) {
+	fileName := writeConfig(t, `
+currencies:
+  symbolMap:
+    EUR: {to: "€"}
+    USD: {to: "$", inFront: true}
+`)
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+
+	diagnostics := config.LintCurrencies(map[string]bool{"EUR": true, "CZK": true})
+	assert.Equal(t, []Diagnostic{{
+		Source:  Source{File: filepath.Clean(fileName), Line: 5},
+		Message: "currency mapping of USD is not used by any transaction",
+	}}, diagnostics)
+}
+
+func TestLint_banks_matching_same_files(t *testing.T) {
+	messages := lintMessages(t, `
+banks:
+  checking:
+    fileNamePattern: '^Fio'
+    identifyingColumns: [Datum, Objem]
+  savings:
+    extends: checking
+  other:
+    identifyingColumns: [datum, OBJEM, Úrok]
+  card:
+    fileNamePattern: '^Card'
+    identifyingColumns: [Datum, Objem]
+`)
+
+	assert.Equal(t, []string{
+		"config.yaml:6: bank `savings' and bank `other' (config.yaml:8) match the same header by identifyingColumns, the files they match need --bank-name or a fileNamePattern",
+		"config.yaml:6: bank `savings' has the same fileNamePattern `^Fio' as bank `checking' (config.yaml:3), the files it matches need --bank-name",
+		"config.yaml:8: bank `other' and bank `card' (config.yaml:10) match the same header by identifyingColumns, the files they match need --bank-name or a fileNamePattern",
+		"config.yaml:8: bank `other' and bank `checking' (config.yaml:3) match the same header by identifyingColumns, the files they match need --bank-name or a fileNamePattern",
+	}, messages)
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Merging of yaml nodes, used to apply local bank settings on top of a
+// preset or the bank it extends.
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// Return the value of the key in a mapping node and its index in
+// Content, or nil and -1
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], i
+		}
+	}
+
+	return nil, -1
+}
+
+// Lists of rules that are concatenated rather than replaced when
+// appending lists.  Other lists, e.g. identifyingColumns, are lists of
+// settings and always replaced.
+var appendableLists = map[string]bool{
+	"twinTransactions":    true,
+	"ignoredTransactions": true,
+	"derivedFields":       true,
+}
+
+// Merge override on top of base and return the result.  Mappings are
+// merged key by key recursively.  The appendable lists are
+// concatenated if appendLists is set, otherwise any other value in
+// override replaces the one in base.  Neither node is modified.
+func mergeNodes(base *yaml.Node, override *yaml.Node, appendLists bool) *yaml.Node {
+	base, override = resolveAlias(base), resolveAlias(override)
+
+	if base == nil {
+		return override
+	}
+
+	if appendLists && base.Kind == yaml.SequenceNode && override.Kind == yaml.SequenceNode {
+		merged := *override
+		merged.Content = append(append([]*yaml.Node{}, base.Content...), override.Content...)
+		return &merged
+	}
+
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+
+	merged := *base
+	merged.Content = append([]*yaml.Node{}, base.Content...)
+
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+
+		if _, index := mappingEntry(&merged, key.Value); index != -1 {
+			merged.Content[index+1] = mergeNodes(merged.Content[index+1], value, appendLists && appendableLists[key.Value])
+		} else {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+
+	return &merged
+}
+
+// Remove the key from a mapping node
+func removeMappingEntry(node *yaml.Node, key string) {
+	if _, index := mappingEntry(node, key); index != -1 {
+		node.Content = append(node.Content[:index:index], node.Content[index+2:]...)
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Values of NoteRule.As
+const (
+	// Appended to the transaction header after the marker
+	NoteAsComment = "comment"
+	// Added as a meta key
+	NoteAsMeta = "meta"
+	// Appended to the header like a comment and the transaction is
+	// marked pending (`!`) instead of cleared
+	NoteAsPending = "pending"
+)
+
+// Values of AmountCondition.Sign
+const (
+	SignIncoming = "incoming"
+	SignOutgoing = "outgoing"
+)
+
+// Marker preceding the notes in the transaction header unless
+// Config.NoteMarker is set, so the notes are easy to find
+const DefaultNoteMarker = "(^.^)"
+
+// Default meta key of notes emitted as meta
+const DefaultNoteKey = "Note"
+
+// List of names, a single name can be given as a string
+type Names []string
+
+func (n *Names) UnmarshalYAML(value *yaml.Node) error {
+	var name string
+	if err := value.Decode(&name); err == nil {
+		*n = Names{name}
+		return nil
+	}
+
+	var names []string
+	if err := value.Decode(&names); err != nil {
+		return err
+	}
+
+	*n = Names(names)
+
+	return nil
+}
+
+// Reminder attached to the transactions matching all the conditions,
+// for example:
+//
+//	notes:
+//	  - payee: [Alza, Tesco]
+//	    text: what did you get from there?
+//	  - group: travel
+//	    text: add to/from/location
+//	  - payee: RegioJet
+//	    sign: incoming
+//	    text: check if credit
+//	    as: pending
+type NoteRule struct {
+	// Payee names the note applies to, any payee if empty
+	Payee Names `yaml:"payee"`
+
+	// Groups of the payees the note applies to, any group if empty
+	Group Names `yaml:"group"`
+
+	// Only apply to the payees of the travel group, see TravelGroup
+	Travel bool `yaml:"travel"`
+
+	// Only apply to the payments of the direction and amount
+	Amount AmountCondition `yaml:",inline"`
+
+	Text string `yaml:"text"`
+
+	// How the note is emitted, `comment` (default), `meta` or
+	// `pending`
+	As string `yaml:"as" enum:"comment,meta,pending"`
+
+	// Meta key of notes emitted as meta, defaults to Note
+	Key string `yaml:"key"`
+
+	// Where the note is defined
+	Source Source `yaml:"-"`
+}
+
+func (n NoteRule) GetKey() string {
+	if n.Key == "" {
+		return DefaultNoteKey
+	}
+
+	return n.Key
+}
+
+func (c Config) GetNoteMarker() string {
+	if c.NoteMarker == nil {
+		return DefaultNoteMarker
+	}
+
+	return *c.NoteMarker
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig_notes(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, `
+noteMarker: TODO
+notes:
+  - payee: Tesco
+    text: what did you get from there?
+  - payee: [Alza, Tiger]
+    minAmount: 1000
+    sign: outgoing
+    text: keep the receipt
+    as: meta
+    key: Receipt
+`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "TODO", config.GetNoteMarker())
+	assert.Equal(t, 2, len(config.Notes))
+	assert.Equal(t, Names{"Tesco"}, config.Notes[0].Payee)
+	assert.Equal(t, DefaultNoteKey, config.Notes[0].GetKey())
+	assert.Equal(t, 4, config.Notes[0].Source.Line)
+
+	note := config.Notes[1]
+	assert.Equal(t, Names{"Alza", "Tiger"}, note.Payee)
+	assert.Equal(t, 1000.0, *note.Amount.MinAmount)
+	assert.Equal(t, SignOutgoing, note.Amount.Sign)
+	assert.Equal(t, NoteAsMeta, note.As)
+	assert.Equal(t, "Receipt", note.GetKey())
+
+	config, err = ReadConfig(writeConfig(t, "payees: {}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultNoteMarker, config.GetNoteMarker())
+}
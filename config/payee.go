@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strings"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,6 +25,16 @@ type PayeePattern struct {
 	Type string
 
 	Meta *map[string]string
+
+	// Dates the pattern matches between, given by the validFrom and
+	// validTo meta keys:
+	//
+	//	payeeRaw:
+	//	  - '^PRE ': {validTo: 2023-04-30}
+	DateRange DateRange
+
+	// Where the pattern is defined
+	Source Source `yaml:"-"`
 }
 
 type PayeePatterns []PayeePattern
@@ -39,6 +51,17 @@ type Payee struct {
 	// specify a template string for dynamically generated accounts.
 	AccountTemplate string `yaml:"accountTemplate"`
 
+	// Accounts of incoming and outgoing payments, e.g. refunds to
+	// Income:Refunds, used instead of Account and AccountTemplate
+	IncomingAccount string `yaml:"incomingAccount"`
+	OutgoingAccount string `yaml:"outgoingAccount"`
+
+	// Accounts of some of the transactions, see AccountRule
+	Accounts []AccountRule `yaml:"accounts"`
+
+	// Dates the payee matches transactions between
+	DateRange DateRange `yaml:",inline"`
+
 	// go text/template template string used to generate the payee text
 	Template string `yaml:"template"`
 
@@ -50,7 +73,47 @@ type Payee struct {
 
 	NoteForMe PayeePatterns `yaml:"noteForMe"`
 
+	// Czech payment symbols.  They are compared exactly, ignoring
+	// leading zeros.
+	VariableSymbol PayeePatterns `yaml:"variableSymbol"`
+	ConstantSymbol PayeePatterns `yaml:"constantSymbol"`
+	SpecificSymbol PayeePatterns `yaml:"specificSymbol"`
+
+	// Patterns matched against custom fields, keyed by the field name
+	Custom map[string]PayeePatterns `yaml:"custom"`
+
 	Meta *map[string]string `yaml:"meta"`
+
+	// Groups the payee belongs to, see Group
+	Groups Names `yaml:"groups"`
+
+	// Ledger tags added to the payee's transactions, including the
+	// tags of its groups
+	Tags []string `yaml:"tags"`
+
+	// Where the payee is defined
+	Source Source `yaml:"-"`
+}
+
+// Pattern fields of the payee by their config name, excluding custom
+// fields
+func (p *Payee) patternFields() map[string]PayeePatterns {
+	return map[string]PayeePatterns{
+		"payeeRaw":              p.PayeeRaw,
+		"receiverAccountNumber": p.ReceiverAccountNumber,
+		"paymentType":           p.PaymentType,
+		"noteForMe":             p.NoteForMe,
+		"variableSymbol":        p.VariableSymbol,
+		"constantSymbol":        p.ConstantSymbol,
+		"specificSymbol":        p.SpecificSymbol,
+	}
+}
+
+// Report whether an account is assigned to the payee, directly, by
+// direction or by account rules
+func (p *Payee) HasAccount() bool {
+	return p.Account != "" || p.AccountTemplate != "" ||
+		p.IncomingAccount != "" || p.OutgoingAccount != "" || len(p.Accounts) > 0
 }
 
 type PayeeConfig struct {
@@ -111,16 +174,33 @@ func (pp *PayeePattern) UnmarshalYAML(value *yaml.Node) error {
 		for key, value := range rawPattern {
 			pp.Value = key
 			pp.Meta = value
-			return nil
+			return pp.extractDateRange()
 		}
 	}
 
 	return fmt.Errorf("Error parsing PayeePattern")
 }
 
+// Normalize a payment symbol: remove whitespace and leading zeros.
+// Symbols consisting only of zeros are treated as empty, many banks
+// export them instead of an empty value.
+func NormalizeSymbol(symbol string) string {
+	return strings.TrimLeft(strings.Join(strings.Fields(symbol), ""), "0")
+}
+
 func GetUnknownPayee(payeeRaw string) *Payee {
 	return &Payee{
 		Name:    "Unknown payee ;" + payeeRaw,
 		Account: "Unknown:Account",
 	}
 }
+
+// Unknown payee of a payment to an account at a known bank.  The bank
+// name is included so the payments to different institutions are
+// distinguishable.
+func GetUnknownPayeeAtBank(payeeRaw string, bankName string) *Payee {
+	return &Payee{
+		Name:    "Unknown payee at " + bankName + " ;" + payeeRaw,
+		Account: "Unknown:Account",
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bank configs of common banks shipped with the binary.  A bank in the
+// config can be based on a preset with the `preset` key, the local
+// settings are merged on top of it.
+
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+var presetNodes map[string]*yaml.Node
+
+func loadPresetNodes() map[string]*yaml.Node {
+	if presetNodes != nil {
+		return presetNodes
+	}
+
+	presetNodes = make(map[string]*yaml.Node)
+
+	entries, err := presetFiles.ReadDir("presets")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		content, err := presetFiles.ReadFile(path.Join("presets", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var document yaml.Node
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			panic(fmt.Sprintf("Preset %s: %s", entry.Name(), err))
+		}
+
+		presetNodes[strings.TrimSuffix(entry.Name(), ".yaml")] = document.Content[0]
+	}
+
+	return presetNodes
+}
+
+// Names of the available presets, sorted
+func PresetNames() []string {
+	var names []string
+	for name := range loadPresetNodes() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// The preset definition as written in the preset file
+func PresetYaml(name string) ([]byte, bool) {
+	content, err := presetFiles.ReadFile(path.Join("presets", name+".yaml"))
+	return content, err == nil
+}
+
+// Decode the preset into a bank config
+func GetPreset(name string) (*Bank, error) {
+	node, exists := loadPresetNodes()[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown preset `%s', available presets: %s", name, strings.Join(PresetNames(), ", "))
+	}
+
+	var bank Bank
+	if err := node.Decode(&bank); err != nil {
+		return nil, fmt.Errorf("preset %s: %s", name, err)
+	}
+	bank.Name = name
+	bank.Preset = name
+
+	return &bank, nil
+}
+
+// Replace banks based on a preset with the preset merged with the
+// local settings.  The node is the config root mapping.
+func (cf *configFiles) applyPresets(root *yaml.Node) error {
+	banks, _ := mappingEntry(root, "banks")
+	banks = resolveAlias(banks)
+	if banks == nil || banks.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(banks.Content); i += 2 {
+		name, bank := banks.Content[i].Value, resolveAlias(banks.Content[i+1])
+		if bank.Kind != yaml.MappingNode {
+			continue
+		}
+
+		preset, _ := mappingEntry(bank, "preset")
+		if preset == nil || preset.Value == "" {
+			continue
+		}
+
+		presetNode, exists := loadPresetNodes()[preset.Value]
+		if !exists {
+			return fmt.Errorf("%s: bank %s: unknown preset `%s', available presets: %s",
+				cf.source(preset), name, preset.Value, strings.Join(PresetNames(), ", "))
+		}
+
+		banks.Content[i+1] = mergeNodes(presetNode, bank, false)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPresets_are_valid(t *testing.T) {
+	names := PresetNames()
+	assert.Equal(t, []string{"airbank", "csob", "fio-csv-2024", "revolut", "wise"}, names)
+
+	for _, name := range names {
+		bank, err := GetPreset(name)
+		assert.Nil(t, err, name)
+		assert.NotEqual(t, "", bank.DisplayName, name)
+		assert.NotEqual(t, "", bank.DatePatternFrom, name)
+		assert.NotEqual(t, 0, len(bank.IdentifyingColumns), name)
+		assert.NotEqual(t, 0, len(bank.ColumnNames.DateRaw), name)
+		assert.NotEqual(t, 0, len(bank.ColumnNames.PayeeRaw), name)
+	}
+}
+
+func TestGetPreset_unknown(t *testing.T) {
+	_, err := GetPreset("nonexistent")
+	assert.NotNil(t, err)
+}
+
+func TestMergeNodes(t *testing.T) {
+	var base, override, expected yaml.Node
+	yaml.Unmarshal([]byte("a: 1\nb: {c: 2, d: [1, 2]}\n"), &base)
+	yaml.Unmarshal([]byte("b: {c: 3, d: [3]}\ne: 4\n"), &override)
+	yaml.Unmarshal([]byte("a: 1\nb: {c: 3, d: [3]}\ne: 4\n"), &expected)
+
+	merged := mergeNodes(base.Content[0], override.Content[0], false)
+
+	var result, expectedValue interface{}
+	merged.Decode(&result)
+	expected.Content[0].Decode(&expectedValue)
+	assert.Equal(t, expectedValue, result)
+
+	// base is not modified
+	var baseValue map[string]interface{}
+	base.Content[0].Decode(&baseValue)
+	assert.Equal(t, 1, baseValue["a"])
+	assert.Equal(t, map[string]interface{}{"c": 2, "d": []interface{}{1, 2}}, baseValue["b"])
+}
+
+func writeConfig(t *testing.T, content string) string {
+	fileName := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func TestReadConfig_bank_with_preset(t *testing.T) {
+	fileName := writeConfig(t, `
+banks:
+  fio:
+    preset: fio-csv-2024
+    accountName: Assets:Fio:Checking
+    columnNames:
+      noteForMe: Komentář
+`)
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+
+	bank := config.Banks["fio"]
+	assert.Equal(t, "fio-csv-2024", bank.Preset)
+	assert.Equal(t, "Assets:Fio:Checking", bank.AccountName)
+	assert.Equal(t, "Fio banka", bank.DisplayName)
+	assert.Equal(t, "02.01.2006", bank.DatePatternFrom)
+	assert.Equal(t, ColumnName{"Komentář"}, bank.ColumnNames.NoteForMe)
+	assert.Equal(t, ColumnName{"Objem"}, bank.ColumnNames.AmountAccount)
+	assert.Equal(t, 1, len(bank.DerivedFields))
+}
+
+func TestReadConfig_unknown_preset(t *testing.T) {
+	fileName := writeConfig(t, `
+banks:
+  fio:
+    preset: fio-1999
+`)
+
+	_, err := ReadConfig(fileName)
+	assert.NotNil(t, err)
+}
+
+func TestGetBankConfig_from_preset(t *testing.T) {
+	records := [][]string{
+		{"Type", "Product", "Started Date", "Completed Date", "Description", "Amount", "Fee", "Currency", "State", "Balance"},
+		{"CARD_PAYMENT", "Current", "2024-01-02 10:00:00", "2024-01-03 10:00:00", "Tesco", "-10.00", "0.00", "EUR", "COMPLETED", "90.00"},
+	}
+
+	bank, found, err := GetBankConfig(records, map[string]*Bank{})
+	assert.True(t, found)
+	assert.Equal(t, "revolut", bank.Name)
+	// presets do not know the account the export belongs to
+	assert.Equal(t, "", bank.AccountName)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "revolut: {preset: revolut, accountName: ...}"))
+}
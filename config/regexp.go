@@ -0,0 +1,196 @@
+package config
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Maximum number of example strings generated for a pattern
+const maxExamples = 16
+
+func parseRegexp(pattern string) (*syntax.Regexp, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.Simplify(), nil
+}
+
+// Parse the pattern the way payee patterns are matched,
+// case-insensitively
+func parsePattern(pattern string) (*syntax.Regexp, error) {
+	return parseRegexp("(?i)" + pattern)
+}
+
+// Minimum number of characters consumed by a match of the expression
+func minLength(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpPlus:
+		return minLength(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * minLength(re.Sub[0])
+	case syntax.OpConcat:
+		length := 0
+		for _, sub := range re.Sub {
+			length += minLength(sub)
+		}
+		return length
+	case syntax.OpAlternate:
+		length := minLength(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			if l := minLength(sub); l < length {
+				length = l
+			}
+		}
+		return length
+	}
+
+	return 0
+}
+
+// Report whether the expression can never match anything, e.g.
+// `foo^bar`, `$x` or an empty character class
+func neverMatches(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return true
+	case syntax.OpCharClass:
+		return len(re.Rune) == 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return neverMatches(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min > 0 && neverMatches(re.Sub[0])
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !neverMatches(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpConcat:
+		consumed := false
+		for i, sub := range re.Sub {
+			if neverMatches(sub) {
+				return true
+			}
+			if sub.Op == syntax.OpBeginText && consumed {
+				return true
+			}
+			if sub.Op == syntax.OpEndText {
+				for _, rest := range re.Sub[i+1:] {
+					if minLength(rest) > 0 {
+						return true
+					}
+				}
+			}
+			if minLength(sub) > 0 {
+				consumed = true
+			}
+		}
+	}
+
+	return false
+}
+
+// A printable rune of the character class, preferring letters and
+// digits
+func classRune(ranges []rune) rune {
+	for _, preferred := range "a0 " {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= preferred && preferred <= ranges[i+1] {
+				return preferred
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i+1] >= ' ' {
+			if ranges[i] < ' ' {
+				return ' '
+			}
+			return ranges[i]
+		}
+	}
+
+	return ranges[0]
+}
+
+// Example strings the expression is likely to match, used to find
+// overlapping patterns.  Zero-width assertions are ignored, so the
+// examples must be checked against the pattern.
+func examples(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return nil
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return nil
+		}
+		return []string{string(classRune(re.Rune))}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		// mostly used for the separator of words, e.g. `www.alza.cz`
+		return []string{" "}
+	case syntax.OpCapture, syntax.OpPlus:
+		return examples(re.Sub[0])
+	case syntax.OpStar, syntax.OpQuest:
+		return []string{""}
+	case syntax.OpRepeat:
+		var result []string
+		for _, example := range examples(re.Sub[0]) {
+			result = append(result, strings.Repeat(example, re.Min))
+		}
+		return result
+	case syntax.OpConcat:
+		result := []string{""}
+		for _, sub := range re.Sub {
+			var next []string
+			for _, prefix := range result {
+				for _, example := range examples(sub) {
+					if len(next) < maxExamples {
+						next = append(next, prefix+example)
+					}
+				}
+			}
+			result = next
+		}
+		return result
+	case syntax.OpAlternate:
+		var result []string
+		for _, sub := range re.Sub {
+			result = append(result, examples(sub)...)
+		}
+		if len(result) > maxExamples {
+			result = result[:maxExamples]
+		}
+		return result
+	}
+
+	return []string{""}
+}
+
+// Example strings the payee pattern matches.  Empty if the pattern is
+// invalid or none of the generated examples matches it.
+func PatternExamples(pattern string) []string {
+	parsed, err := parsePattern(pattern)
+	if err != nil {
+		return nil
+	}
+	re := regexp.MustCompile("(?i)" + pattern)
+
+	var result []string
+	for _, example := range examples(parsed) {
+		if re.MatchString(example) {
+			result = append(result, example)
+		}
+	}
+
+	return result
+}
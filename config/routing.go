@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conditions on the direction and the absolute amount of a
+// transaction in the account currency
+type AmountCondition struct {
+	// Bounds of the absolute amount, both inclusive
+	MinAmount *float64 `yaml:"minAmount"`
+	MaxAmount *float64 `yaml:"maxAmount"`
+
+	// Only `incoming` or `outgoing` payments
+	Sign string `yaml:"sign" enum:"incoming,outgoing"`
+}
+
+func (c AmountCondition) IsEmpty() bool {
+	return c.MinAmount == nil && c.MaxAmount == nil && c.Sign == ""
+}
+
+// Report whether every transaction satisfying the other condition
+// satisfies the condition
+func (c AmountCondition) Covers(other AmountCondition) bool {
+	if c.Sign != "" && c.Sign != other.Sign {
+		return false
+	}
+	if c.MinAmount != nil && (other.MinAmount == nil || *c.MinAmount > *other.MinAmount) {
+		return false
+	}
+	if c.MaxAmount != nil && (other.MaxAmount == nil || *c.MaxAmount < *other.MaxAmount) {
+		return false
+	}
+
+	return true
+}
+
+// e.g. `outgoing, amount 10000..`
+func (c AmountCondition) String() string {
+	var parts []string
+	if c.Sign != "" {
+		parts = append(parts, c.Sign)
+	}
+	if c.MinAmount != nil || c.MaxAmount != nil {
+		amount := "amount "
+		if c.MinAmount != nil {
+			amount += fmt.Sprintf("%g", *c.MinAmount)
+		}
+		amount += ".."
+		if c.MaxAmount != nil {
+			amount += fmt.Sprintf("%g", *c.MaxAmount)
+		}
+		parts = append(parts, amount)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Account of a payee used for some of its transactions, by the date,
+// direction or amount, e.g. after moving apartments or for transfers
+// to savings:
+//
+//	Electricity:
+//	  payeeRaw: '^PRE '
+//	  accounts:
+//	    - account: Expenses:Flat1:Utilities
+//	      validTo: 2023-04-30
+//	    - account: Expenses:Flat2:Utilities
+//	      validFrom: 2023-05-01
+//	Me:
+//	  receiverAccountNumber: 2000145399/2010
+//	  account: Assets:Other
+//	  accounts:
+//	    - sign: outgoing
+//	      minAmount: 10000
+//	      account: Assets:Savings
+//
+// The first rule matching the transaction is used, then the payee's
+// incomingAccount or outgoingAccount, then its account or
+// accountTemplate.
+type AccountRule struct {
+	DateRange DateRange       `yaml:",inline"`
+	Amount    AmountCondition `yaml:",inline"`
+
+	Account         string `yaml:"account"`
+	AccountTemplate string `yaml:"accountTemplate"`
+
+	// Where the rule is defined
+	Source Source `yaml:"-"`
+}
+
+// Report whether every transaction matching the other rule matches
+// the rule
+func (r AccountRule) Covers(other AccountRule) bool {
+	return r.DateRange.Covers(other.DateRange) && r.Amount.Covers(other.Amount)
+}
+
+// The conditions of the rule, e.g. `outgoing, amount 10000.., 2023-05-01..`
+func (r AccountRule) Conditions() string {
+	var parts []string
+	if !r.Amount.IsEmpty() {
+		parts = append(parts, r.Amount.String())
+	}
+	if r.DateRange.IsBounded() {
+		parts = append(parts, r.DateRange.String())
+	}
+	if len(parts) == 0 {
+		return "always"
+	}
+
+	return strings.Join(parts, ", ")
+}
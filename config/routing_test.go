@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const routingConfig = `
+payees:
+  Alza:
+    payeeRaw: '^alza'
+    account: Expenses:Electronics
+    incomingAccount: Income:Refunds
+  Me:
+    receiverAccountNumber: 2000145399/2010
+    account: Assets:Other
+    accounts:
+      - sign: outgoing
+        minAmount: 10000
+        account: Assets:Savings
+      - sign: outgoing
+        maxAmount: 500
+        validFrom: 2024-01-01
+        account: Expenses:Pocket
+`
+
+func TestReadConfig_account_rules(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, routingConfig))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Income:Refunds", config.Payees["Alza"].IncomingAccount)
+
+	rules := config.Payees["Me"].Accounts
+	assert.Equal(t, 2, len(rules))
+	assert.Equal(t, SignOutgoing, rules[0].Amount.Sign)
+	assert.Equal(t, 10000.0, *rules[0].Amount.MinAmount)
+	assert.Equal(t, "outgoing, amount 10000..", rules[0].Conditions())
+	assert.Equal(t, "outgoing, amount ..500, 2024-01-01..", rules[1].Conditions())
+	assert.Equal(t, "always", AccountRule{}.Conditions())
+}
+
+func TestAccountRule_Covers(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, routingConfig))
+	assert.Nil(t, err)
+	rules := config.Payees["Me"].Accounts
+
+	assert.False(t, rules[0].Covers(rules[1]))
+	assert.False(t, rules[1].Covers(rules[0]))
+	assert.True(t, AccountRule{}.Covers(rules[1]))
+	assert.True(t, AccountRule{Amount: AmountCondition{Sign: SignOutgoing}}.Covers(rules[1]))
+	assert.False(t, AccountRule{Amount: AmountCondition{Sign: SignIncoming}}.Covers(rules[1]))
+}
+
+func TestLint_account_rules(t *testing.T) {
+	messages := lintMessages(t, routingConfig+`
+      - sign: outgoing
+        minAmount: 20000
+        account: Assets:Savings:Big
+      - minAmount: 100
+        maxAmount: 10
+        account: Expenses:Odd
+  Employer:
+    payeeRaw: '^employer'
+    incomingAccount: Income:Salary
+`)
+
+	assert.Equal(t, []string{
+		"config.yaml:19: account rule 2 of payee `Me' (outgoing, amount 20000..) is never used, rule 0 (outgoing, amount 10000..) matches all its transactions",
+		"config.yaml:22: account rule 3 of payee `Me' has minAmount 100 greater than its maxAmount 10",
+		"config.yaml:25: payee `Employer' has no account for outgoing payments",
+	}, messages, strings.Join(messages, "\n"))
+}
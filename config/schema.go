@@ -0,0 +1,420 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSON Schema of the config file, generated from the Config type.
+// Field names are taken from the yaml tags, the allowed values of
+// string fields from the `enum` tags (comma separated; for maps and
+// lists they apply to the values).  Types with custom unmarshalling
+// are described by schemaGenerator.override.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	MinProperties        int                `json:"minProperties,omitempty"`
+	MaxProperties        int                `json:"maxProperties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+func stringSchema() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func stringListSchema() *Schema {
+	return &Schema{Type: "array", Items: stringSchema()}
+}
+
+func definitionRef(name string) *Schema {
+	return &Schema{Ref: "#/definitions/" + name}
+}
+
+// Schema of the types with custom UnmarshalYAML and of the recursive
+// accounts hierarchy, nil for other types
+func (g *schemaGenerator) override(t reflect.Type) *Schema {
+	switch t {
+	case reflect.TypeOf(Account{}):
+		g.define("account", func() *Schema {
+			return &Schema{
+				Type: "object",
+				AdditionalProperties: &Schema{AnyOf: []*Schema{
+					{Type: "null"},
+					stringSchema(),
+					stringListSchema(),
+					definitionRef("account"),
+				}},
+			}
+		})
+		return definitionRef("account")
+	case reflect.TypeOf(Payee{}):
+		g.define("payee", func() *Schema {
+			return &Schema{AnyOf: []*Schema{
+				stringSchema(),
+				stringListSchema(),
+				g.structSchema(t),
+			}}
+		})
+		return definitionRef("payee")
+	case reflect.TypeOf(PayeePattern{}):
+		g.define("pattern", func() *Schema {
+			return &Schema{AnyOf: []*Schema{
+				stringSchema(),
+				// pattern with meta, `pattern: {key: value}`
+				{
+					Type:          "object",
+					MinProperties: 1,
+					MaxProperties: 1,
+					AdditionalProperties: &Schema{AnyOf: []*Schema{
+						{Type: "null"},
+						{Type: "object", AdditionalProperties: stringSchema()},
+					}},
+				},
+			}}
+		})
+		return definitionRef("pattern")
+	case reflect.TypeOf(PayeePatterns{}):
+		pattern := g.schema(reflect.TypeOf(PayeePattern{}), nil)
+		return &Schema{AnyOf: []*Schema{
+			pattern,
+			{Type: "array", Items: pattern},
+		}}
+	case reflect.TypeOf(Date{}):
+		return stringSchema()
+	case reflect.TypeOf(ColumnName{}), reflect.TypeOf(Names{}):
+		return &Schema{AnyOf: []*Schema{stringSchema(), stringListSchema()}}
+	}
+
+	return nil
+}
+
+type schemaGenerator struct {
+	definitions map[string]*Schema
+}
+
+// Add the definition unless it exists.  The placeholder allows
+// recursive definitions.
+func (g *schemaGenerator) define(name string, build func() *Schema) {
+	if _, exists := g.definitions[name]; exists {
+		return
+	}
+
+	g.definitions[name] = &Schema{}
+	g.definitions[name] = build()
+}
+
+// Config key of the struct field, empty if the field is not read
+// from the config.  Inline structs are handled by structSchema.
+func fieldKey(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+	if tag == "" {
+		return strings.ToLower(field.Name)
+	}
+
+	return tag
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Contains(field.Tag.Get("yaml"), ",inline") {
+			for key, property := range g.structSchema(field.Type).Properties {
+				schema.Properties[key] = property
+			}
+		} else if key := fieldKey(field); key != "" {
+			var enum []string
+			if tag := field.Tag.Get("enum"); tag != "" {
+				enum = strings.Split(tag, ",")
+			}
+			schema.Properties[key] = g.schema(field.Type, enum)
+		}
+	}
+
+	return schema
+}
+
+func (g *schemaGenerator) schema(t reflect.Type, enum []string) *Schema {
+	if schema := g.override(t); schema != nil {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem(), enum)
+	case reflect.String:
+		return &Schema{Type: "string", Enum: enum}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: g.schema(t.Elem(), enum)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem(), enum)}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	panic(fmt.Sprintf("no schema for type %s", t))
+}
+
+// Generate the JSON Schema of the config file
+func ConfigSchema() *Schema {
+	g := &schemaGenerator{definitions: make(map[string]*Schema)}
+
+	schema := g.structSchema(reflect.TypeOf(Config{}))
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "bank-to-ledger config"
+	schema.Properties["include"] = &Schema{AnyOf: []*Schema{stringSchema(), stringListSchema()}}
+	schema.Definitions = g.definitions
+
+	return schema
+}
+
+// The config schema as indented JSON
+func ConfigSchemaJson() ([]byte, error) {
+	return json.MarshalIndent(ConfigSchema(), "", "  ")
+}
+
+func (s *Schema) resolve(root *Schema) *Schema {
+	for s.Ref != "" {
+		s = root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+
+	return s
+}
+
+// Report whether the node kind is one of the schema's types, ignoring
+// the nested values
+func (s *Schema) acceptsKind(node *yaml.Node, root *Schema) bool {
+	s = s.resolve(root)
+
+	if len(s.AnyOf) > 0 {
+		for _, alternative := range s.AnyOf {
+			if alternative.acceptsKind(node, root) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch s.Type {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "null":
+		return isNull(node)
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	case "string":
+		return node.Kind == yaml.ScalarNode
+	}
+
+	return true
+}
+
+// Human readable list of the schema's types
+func (s *Schema) describe(root *Schema) string {
+	s = s.resolve(root)
+
+	if len(s.AnyOf) == 0 {
+		switch s.Type {
+		case "object":
+			return "a mapping"
+		case "array":
+			return "a list"
+		case "integer":
+			return "an integer"
+		case "null":
+			return "empty"
+		}
+		return "a " + s.Type
+	}
+
+	var types []string
+	for _, alternative := range s.AnyOf {
+		types = append(types, alternative.describe(root))
+	}
+
+	return strings.Join(types, " or ")
+}
+
+type schemaValidator struct {
+	root   *Schema
+	source func(*yaml.Node) Source
+	errors []string
+}
+
+func (v *schemaValidator) report(node *yaml.Node, path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "config"
+	}
+	v.errors = append(v.errors, fmt.Sprintf("%s: %s: %s", v.source(node), path, fmt.Sprintf(format, args...)))
+}
+
+func (v *schemaValidator) validate(s *Schema, node *yaml.Node, path string) {
+	s = s.resolve(v.root)
+	node = resolveAlias(node)
+
+	// empty values decode to zero values
+	if isNull(node) {
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		// validate against the first alternative of the node's kind
+		for _, alternative := range s.AnyOf {
+			if alternative.acceptsKind(node, v.root) {
+				v.validate(alternative, node, path)
+				return
+			}
+		}
+		v.report(node, path, "expected %s", s.describe(v.root))
+		return
+	}
+
+	if !s.acceptsKind(node, v.root) {
+		v.report(node, path, "expected %s", s.describe(v.root))
+		return
+	}
+
+	if len(s.Enum) > 0 && !containsString(s.Enum, node.Value) {
+		v.report(node, path, "`%s' is not one of %s", node.Value, strings.Join(s.Enum, ", "))
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		count := len(node.Content) / 2
+		if s.MinProperties > 0 && count < s.MinProperties || s.MaxProperties > 0 && count > s.MaxProperties {
+			v.report(node, path, "expected a mapping with a single key")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+
+			if property, exists := s.Properties[key.Value]; exists {
+				v.validate(property, value, keyPath)
+			} else if additional, ok := s.AdditionalProperties.(*Schema); ok {
+				v.validate(additional, value, keyPath)
+			} else if s.AdditionalProperties == false {
+				v.report(key, keyPath, "unknown key%s", v.didYouMean(key.Value, s.Properties))
+			}
+		}
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range node.Content {
+				v.validate(s.Items, item, fmt.Sprintf("%s.%d", path, i))
+			}
+		}
+	}
+}
+
+// Number of single character edits changing a to b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = current[j-1] + 1
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+// Suggest the known key closest to a misspelled one, e.g. `payeRaw'
+func (v *schemaValidator) didYouMean(key string, properties map[string]*Schema) string {
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 3
+	for _, name := range names {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(name)); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(", did you mean `%s'?", best)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate the root node of a config file against the config schema
+func (cf *configFiles) validate(root *yaml.Node) error {
+	v := &schemaValidator{root: ConfigSchema(), source: cf.source}
+	v.validate(v.root, root, "")
+
+	if len(v.errors) > 0 {
+		return fmt.Errorf("invalid config:\n%s", strings.Join(v.errors, "\n"))
+	}
+
+	return nil
+}
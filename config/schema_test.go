@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func validateYaml(t *testing.T, content string) []string {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := newConfigFiles()
+	cf.markNodes(document.Content[0], "config.yaml")
+
+	err := cf.validate(document.Content[0])
+	if err == nil {
+		return nil
+	}
+
+	return strings.Split(err.Error(), "\n")[1:]
+}
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+
+	assert.Equal(t, "#/definitions/payee", schema.Properties["payees"].AdditionalProperties.(*Schema).Ref)
+	assert.Equal(t, "#/definitions/account", schema.Properties["accounts"].Ref)
+
+	bank := schema.Properties["banks"].AdditionalProperties.(*Schema)
+	assert.Equal(t, false, bank.AdditionalProperties)
+	assert.Equal(t, "integer", bank.Properties["columnIndices"].Properties["payeeRaw"].Type)
+	assert.Equal(t, []string{"sum", "merge"}, bank.Properties["twinTransactions"].Items.Properties["type"].Enum)
+	assert.Nil(t, bank.Properties["source"])
+
+	_, err := ConfigSchemaJson()
+	assert.Nil(t, err)
+}
+
+func TestConfigSchemaJson(t *testing.T) {
+	content, err := ConfigSchemaJson()
+	assert.Nil(t, err)
+
+	var schema map[string]interface{}
+	assert.Nil(t, json.Unmarshal(content, &schema))
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema["$schema"])
+}
+
+func TestValidate_payee_forms(t *testing.T) {
+	errors := validateYaml(t, `
+include: [banks.yaml]
+payees:
+  Tesco: '^tesco'
+  Albert: ['^albert', '^ahold']
+  Billa:
+    payeeRaw:
+      - '^billa'
+      - '^billa praha': {location: Prague}
+    variableSymbol: 42
+    custom:
+      merchant: '^billa'
+    meta: {category: food}
+accounts:
+  Expenses:
+    self: Misc
+    Groceries: [Tesco, Albert]
+    Rent:
+`)
+
+	assert.Equal(t, 0, len(errors), strings.Join(errors, "\n"))
+}
+
+func TestValidate_errors(t *testing.T) {
+	errors := validateYaml(t, `
+payees:
+  Tesco:
+    payeRaw: '^tesco'
+  Albert:
+    payeeRaw:
+      - {'^a': {}, '^b': {}}
+banks:
+  fio:
+    skipRows: two
+    twinTransactions:
+      - type: summ
+    directionValues: {D: debet}
+`)
+
+	assert.Equal(t, []string{
+		"config.yaml:4: payees.Tesco.payeRaw: unknown key, did you mean `payeeRaw'?",
+		"config.yaml:7: payees.Albert.payeeRaw.0: expected a mapping with a single key",
+		"config.yaml:10: banks.fio.skipRows: expected an integer",
+		"config.yaml:12: banks.fio.twinTransactions.0.type: `summ' is not one of sum, merge",
+		"config.yaml:13: banks.fio.directionValues.D: `debet' is not one of debit, credit",
+	}, errors)
+}
+
+func TestValidate_presets(t *testing.T) {
+	for _, name := range PresetNames() {
+		content, _ := PresetYaml(name)
+		errors := validateYaml(t, "banks:\n  "+name+":\n"+indent(string(content), "    "))
+		assert.Equal(t, 0, len(errors), name+": "+strings.Join(errors, "\n"))
+	}
+}
+
+func indent(text string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestReadConfig_invalid(t *testing.T) {
+	_, err := ReadConfig(writeConfig(t, `
+payees:
+  Tesco: {acount: Expenses:Groceries}
+`))
+
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "config.yaml:3: payees.Tesco.acount: unknown key, did you mean `account'?"), err.Error())
+}
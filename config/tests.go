@@ -0,0 +1,50 @@
+package config
+
+// Test case of the config, checked by the test-config command.  The
+// transaction is built from the given fields and run through payee
+// matching, account resolution, ignoring and twin detection.  Empty
+// expectations are not checked.
+//
+//	tests:
+//	  - payeeRaw: TESCO 1234 PRAHA
+//	    amount: -250
+//	    expectPayee: Tesco
+//	    expectAccount: Expenses:Groceries
+type ConfigTest struct {
+	// Name of the bank the transaction is from, its ignored and twin
+	// transactions are checked.  Defaults to an empty bank.
+	Bank string `yaml:"bank"`
+
+	PayeeRaw string `yaml:"payeeRaw"`
+
+	// Signed amount in the account currency, negative for payments
+	Amount *float64 `yaml:"amount"`
+
+	Currency string `yaml:"currency"`
+
+	// Date of the transaction, YYYY-MM-DD, to test date ranges.
+	// Without it, date-bounded payees, patterns and account rules do
+	// not apply.
+	Date *Date `yaml:"date"`
+
+	// Note for me
+	Note string `yaml:"note"`
+
+	// Other transaction fields by their config names (see
+	// DerivedField), e.g. paymentType or variableSymbol.  Unknown
+	// names are set as custom fields.
+	Fields map[string]string `yaml:"fields"`
+
+	ExpectPayee string `yaml:"expectPayee"`
+
+	ExpectAccount string `yaml:"expectAccount"`
+
+	ExpectIgnored *bool `yaml:"expectIgnored"`
+
+	// Type of the twin transaction the transaction is an anchor of,
+	// `sum`, `merge` or `none`
+	ExpectTwin string `yaml:"expectTwin" enum:"sum,merge,none"`
+
+	// Where the test is defined
+	Source Source `yaml:"-"`
+}
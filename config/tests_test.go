@@ -0,0 +1,44 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig_tests(t *testing.T) {
+	fileName := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: tests.yaml
+payees:
+  Tesco: '^tesco'
+tests:
+  - payeeRaw: TESCO 1234 PRAHA
+    amount: -250
+    date: 2023-05-01
+    expectPayee: Tesco
+`,
+		"tests.yaml": `tests:
+  - payeeRaw: Billa
+    fields: {paymentType: Card}
+    expectIgnored: false
+`,
+	})
+
+	config, err := ReadConfig(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(config.Tests))
+
+	test := config.Tests[0]
+	assert.Equal(t, "TESCO 1234 PRAHA", test.PayeeRaw)
+	assert.Equal(t, -250.0, *test.Amount)
+	assert.Equal(t, "2023-05-01", test.Date.String())
+	assert.Equal(t, "Tesco", test.ExpectPayee)
+	assert.Equal(t, Source{File: fileName, Line: 5}, test.Source)
+
+	test = config.Tests[1]
+	assert.Equal(t, map[string]string{"paymentType": "Card"}, test.Fields)
+	assert.Nil(t, test.Date)
+	assert.False(t, *test.ExpectIgnored)
+	assert.Equal(t, Source{File: filepath.Join(filepath.Dir(fileName), "tests.yaml"), Line: 2}, test.Source)
+}
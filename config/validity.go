@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layout of the dates in the config
+const DateLayout = "2006-01-02"
+
+// A calendar date in the config, e.g. `2023-05-01`
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalYAML(value *yaml.Node) error {
+	date, err := time.Parse(DateLayout, value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid date `%s', expected YYYY-MM-DD", value.Line, value.Value)
+	}
+
+	d.Time = date
+	return nil
+}
+
+func (d *Date) String() string {
+	if d == nil {
+		return ""
+	}
+
+	return d.Format(DateLayout)
+}
+
+// Dates a payee, pattern or account is valid between, both inclusive.
+// A missing bound is open.
+type DateRange struct {
+	ValidFrom *Date `yaml:"validFrom"`
+	ValidTo   *Date `yaml:"validTo"`
+}
+
+func (r DateRange) IsBounded() bool {
+	return r.ValidFrom != nil || r.ValidTo != nil
+}
+
+// Report whether the date is in the range.  Only the calendar date is
+// compared.
+func (r DateRange) Contains(date time.Time) bool {
+	day := date.Format(DateLayout)
+
+	if r.ValidFrom != nil && day < r.ValidFrom.String() {
+		return false
+	}
+	if r.ValidTo != nil && day > r.ValidTo.String() {
+		return false
+	}
+
+	return true
+}
+
+// Report whether some date is in both ranges
+func (r DateRange) Overlaps(other DateRange) bool {
+	if r.ValidFrom != nil && other.ValidTo != nil && r.ValidFrom.After(other.ValidTo.Time) {
+		return false
+	}
+	if other.ValidFrom != nil && r.ValidTo != nil && other.ValidFrom.After(r.ValidTo.Time) {
+		return false
+	}
+
+	return true
+}
+
+// Report whether every date of the other range is in the range
+func (r DateRange) Covers(other DateRange) bool {
+	if r.ValidFrom != nil && (other.ValidFrom == nil || r.ValidFrom.After(other.ValidFrom.Time)) {
+		return false
+	}
+	if r.ValidTo != nil && (other.ValidTo == nil || r.ValidTo.Before(other.ValidTo.Time)) {
+		return false
+	}
+
+	return true
+}
+
+// e.g. `2023-05-01..2024-04-30`, `..2023-04-30`
+func (r DateRange) String() string {
+	return r.ValidFrom.String() + ".." + r.ValidTo.String()
+}
+
+// Move the validFrom and validTo keys of the pattern's meta to its
+// date range
+func (pp *PayeePattern) extractDateRange() error {
+	if pp.Meta == nil {
+		return nil
+	}
+
+	meta := *pp.Meta
+	for key, bound := range map[string]**Date{"validFrom": &pp.DateRange.ValidFrom, "validTo": &pp.DateRange.ValidTo} {
+		value, exists := meta[key]
+		if !exists {
+			continue
+		}
+
+		date, err := time.Parse(DateLayout, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s `%s' of pattern `%s', expected YYYY-MM-DD", key, value, pp.Value)
+		}
+		*bound = &Date{date}
+		delete(meta, key)
+	}
+
+	if len(meta) == 0 {
+		pp.Meta = nil
+	}
+
+	return nil
+}
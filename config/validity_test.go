@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validityConfig = `
+payees:
+  Electricity:
+    receiverAccountNumber:
+      - '1234/0100': {validTo: 2023-04-30}
+      - '5678/0300': {validFrom: 2023-05-01, note: new supplier}
+    accounts:
+      - account: Expenses:Flat1:Utilities
+        validTo: 2023-04-30
+      - accountTemplate: 'Expenses:Flat2:{{ .Payee.Name }}'
+        validFrom: 2023-05-01
+  Gym:
+    payeeRaw: '^gym'
+    account: Expenses:Sport
+    validFrom: 2022-01-01
+`
+
+func date(value string) time.Time {
+	d, _ := time.Parse(DateLayout, value)
+	return d
+}
+
+func TestReadConfig_date_ranges(t *testing.T) {
+	config, err := ReadConfig(writeConfig(t, validityConfig))
+	assert.Nil(t, err)
+
+	electricity := config.Payees["Electricity"]
+	numbers := electricity.ReceiverAccountNumber
+	assert.Equal(t, "..2023-04-30", numbers[0].DateRange.String())
+	assert.Nil(t, numbers[0].Meta)
+	assert.Equal(t, "2023-05-01..", numbers[1].DateRange.String())
+	assert.Equal(t, map[string]string{"note": "new supplier"}, *numbers[1].Meta)
+
+	assert.Equal(t, 2, len(electricity.Accounts))
+	assert.Equal(t, "Expenses:Flat1:Utilities", electricity.Accounts[0].Account)
+	assert.Equal(t, "2023-05-01..", electricity.Accounts[1].DateRange.String())
+	assert.Equal(t, 8, electricity.Accounts[0].Source.Line)
+	assert.True(t, electricity.HasAccount())
+
+	assert.Equal(t, "2022-01-01..", config.Payees["Gym"].DateRange.String())
+}
+
+func TestReadConfig_invalid_date(t *testing.T) {
+	_, err := ReadConfig(writeConfig(t, `
+payees:
+  Gym:
+    payeeRaw: ['^gym': {validFrom: 1.1.2022}]
+`))
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "invalid validFrom `1.1.2022'"), err.Error())
+}
+
+func TestDateRange_Contains(t *testing.T) {
+	r := DateRange{ValidFrom: &Date{date("2023-05-01")}, ValidTo: &Date{date("2023-05-31")}}
+
+	assert.False(t, r.Contains(date("2023-04-30")))
+	assert.True(t, r.Contains(date("2023-05-01")))
+	assert.True(t, r.Contains(date("2023-05-31").Add(23*time.Hour)))
+	assert.False(t, r.Contains(date("2023-06-01")))
+	assert.True(t, DateRange{}.Contains(date("1999-01-01")))
+}
+
+func TestDateRange_Overlaps(t *testing.T) {
+	until := DateRange{ValidTo: &Date{date("2023-04-30")}}
+	from := DateRange{ValidFrom: &Date{date("2023-05-01")}}
+
+	assert.False(t, until.Overlaps(from))
+	assert.False(t, from.Overlaps(until))
+	assert.True(t, until.Overlaps(DateRange{}))
+	assert.True(t, from.Overlaps(DateRange{ValidTo: &Date{date("2023-05-01")}}))
+}
+
+func TestLint_date_ranges(t *testing.T) {
+	messages := lintMessages(t, validityConfig+`
+  OldGym:
+    payeeRaw: '^gym'
+    account: Expenses:Sport
+    validTo: 2021-12-31
+  Water:
+    payeeRaw:
+      - '^water': {validFrom: 2023-02-01, validTo: 2023-01-01}
+    accounts:
+      - account: Expenses:Flat1:Water
+      - validFrom: 2023-05-01
+`)
+
+	assert.Equal(t, []string{
+		"config.yaml:23: pattern `^water' of payee `Water' is valid from 2023-02-01, after its validTo 2023-01-01",
+		"config.yaml:26: account rule 1 of payee `Water' (2023-05-01..) is never used, rule 0 (always) matches all its transactions",
+		"config.yaml:26: account rule 1 of payee `Water' has no account",
+	}, messages, strings.Join(messages, "\n"))
+}
@@ -0,0 +1,214 @@
+package detect
+
+import (
+	cfg "bank-to-ledger/config"
+	t "bank-to-ledger/transaction"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values of a transaction row confirmed by the user.  Empty values
+// are not checked.
+type Confirmation struct {
+	// Transaction row, 1 is the first row after the header
+	Row int `yaml:"row"`
+
+	// Date in YYYY-MM-DD format
+	Date string `yaml:"date"`
+
+	Payee string `yaml:"payee"`
+
+	// Signed amount in the account currency, negative for payments
+	Amount *float64 `yaml:"amount"`
+
+	Currency string `yaml:"currency"`
+
+	// Text contained in one of the notes
+	Note string `yaml:"note"`
+}
+
+// Result of checking one confirmed value against the guessed config
+type Check struct {
+	Row      int
+	Field    string
+	Expected string
+	Actual   string
+	OK       bool
+}
+
+func LoadConfirmations(fileName string) ([]Confirmation, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirmations []Confirmation
+	if err := yaml.Unmarshal(content, &confirmations); err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+
+	return confirmations, nil
+}
+
+// The csv record of the confirmed row, nil if out of range
+func (g *Guess) row(c Confirmation) []string {
+	index := g.DataStart + c.Row - 1
+	if c.Row < 1 || index >= g.DataEnd || len(g.Records[index]) != len(g.Columns) {
+		return nil
+	}
+
+	return g.Records[index]
+}
+
+func parseAmount(value string) (float64, bool) {
+	value = strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(value), ",", "."), " ", "")
+	amount, err := strconv.ParseFloat(value, 64)
+	return amount, err == nil
+}
+
+func sameAmount(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
+
+// Pick the candidate with most confirmed values.  The current choice
+// is kept on ties.
+func (g *Guess) pick(current int, candidates []int, confirmations []Confirmation, matches func(value string, c Confirmation) bool) int {
+	best, bestCount := current, -1
+	for _, candidate := range append([]int{current}, candidates...) {
+		if candidate == -1 {
+			continue
+		}
+
+		count := 0
+		for _, c := range confirmations {
+			if row := g.row(c); row != nil && matches(row[candidate], c) {
+				count++
+			}
+		}
+
+		if count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+
+	return best
+}
+
+// Use the confirmed rows to choose among the candidate columns and
+// date layouts
+func (g *Guess) Apply(confirmations []Confirmation) {
+	ci := &g.Bank.ColumnIndices
+
+	dated := confirmationsWith(confirmations, func(c Confirmation) bool { return c.Date != "" })
+	if len(dated) > 0 && len(g.DateCandidates) > 0 {
+		bestCount := -1
+		for _, index := range append([]int{ci.DateRaw}, g.DateCandidates...) {
+			for _, layout := range g.Columns[index].DateLayouts {
+				count := 0
+				for _, c := range dated {
+					if row := g.row(c); row != nil {
+						date, err := time.Parse(layout, strings.TrimSpace(row[index]))
+						if err == nil && date.Format("2006-01-02") == c.Date {
+							count++
+						}
+					}
+				}
+				if count > bestCount {
+					bestCount = count
+					ci.DateRaw = index
+					g.Bank.DatePatternFrom = layout
+				}
+			}
+		}
+	}
+
+	withAmount := confirmationsWith(confirmations, func(c Confirmation) bool { return c.Amount != nil })
+	if len(withAmount) > 0 && ci.AmountAccount != -1 {
+		ci.AmountAccount = g.pick(ci.AmountAccount, g.AmountCandidates, withAmount, func(value string, c Confirmation) bool {
+			amount, ok := parseAmount(value)
+			return ok && sameAmount(math.Abs(amount), math.Abs(*c.Amount))
+		})
+	}
+
+	withPayee := confirmationsWith(confirmations, func(c Confirmation) bool { return c.Payee != "" })
+	if len(withPayee) > 0 && ci.PayeeRaw != -1 {
+		ci.PayeeRaw = g.pick(ci.PayeeRaw, g.PayeeCandidates, withPayee, func(value string, c Confirmation) bool {
+			return strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(c.Payee))
+		})
+		g.updateNotes()
+	}
+
+	withNote := confirmationsWith(confirmations, func(c Confirmation) bool { return c.Note != "" })
+	if len(withNote) > 0 && ci.NoteForMe != -1 {
+		noteForMe := g.pick(ci.NoteForMe, g.NoteCandidates, withNote, func(value string, c Confirmation) bool {
+			return strings.Contains(strings.ToLower(value), strings.ToLower(c.Note))
+		})
+		if noteForMe == ci.NoteForReceiver {
+			ci.NoteForReceiver = ci.NoteForMe
+		}
+		ci.NoteForMe = noteForMe
+	}
+}
+
+func confirmationsWith(confirmations []Confirmation, filter func(Confirmation) bool) []Confirmation {
+	var result []Confirmation
+	for _, c := range confirmations {
+		if filter(c) {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// Check the confirmed values against the transactions read with the
+// guessed config
+func (g *Guess) Score(confirmations []Confirmation) []Check {
+	var checks []Check
+
+	for _, c := range confirmations {
+		record := g.row(c)
+		if record == nil {
+			checks = append(checks, Check{Row: c.Row, Field: "row", Expected: "transaction row", Actual: "out of range"})
+			continue
+		}
+
+		trans := t.FromCsvRecord(record, cfg.Config{}, &g.Bank)
+		add := func(field string, expected string, actual string, ok bool) {
+			checks = append(checks, Check{Row: c.Row, Field: field, Expected: expected, Actual: actual, OK: ok})
+		}
+
+		if c.Date != "" {
+			actual := ""
+			if date, err := time.Parse(g.Bank.DatePatternFrom, strings.TrimSpace(trans.DateRaw)); err == nil {
+				actual = date.Format("2006-01-02")
+			}
+			add("date", c.Date, actual, actual == c.Date)
+		}
+
+		if c.Payee != "" {
+			add("payee", c.Payee, trans.PayeeRaw, strings.EqualFold(strings.TrimSpace(trans.PayeeRaw), strings.TrimSpace(c.Payee)))
+		}
+
+		if c.Amount != nil {
+			add("amount", fmt.Sprintf("%.2f", *c.Amount), fmt.Sprintf("%.2f", trans.AmountAccount), sameAmount(trans.AmountAccount, *c.Amount))
+		}
+
+		if c.Currency != "" {
+			add("currency", c.Currency, trans.CurrencyAccount, strings.EqualFold(trans.CurrencyAccount, c.Currency))
+		}
+
+		if c.Note != "" {
+			notes := strings.TrimSpace(trans.NoteForMe + " " + trans.NoteForReceiver)
+			add("note", c.Note, notes, strings.Contains(strings.ToLower(notes), strings.ToLower(c.Note)))
+		}
+	}
+
+	return checks
+}
@@ -0,0 +1,38 @@
+package detect
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// Parse the csv content.  Lenient parsing allows rows of different
+// width, e.g. preamble and footer rows, and stray quotes.
+func ParseCsv(content []byte, comma rune, lenient bool) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = comma
+	if lenient {
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+	}
+
+	return reader.ReadAll()
+}
+
+// The most common width of rows with at least two fields and the
+// number of rows having it.  Used to guess the delimiter of files with
+// rows of varying width.
+func ModalWidth(records [][]string) (int, int) {
+	widths := make(map[int]int)
+	best, count := 0, 0
+	for _, record := range records {
+		if len(record) < 2 {
+			continue
+		}
+		widths[len(record)]++
+		if widths[len(record)] > count || (widths[len(record)] == count && len(record) > best) {
+			best, count = len(record), widths[len(record)]
+		}
+	}
+
+	return best, count
+}
@@ -0,0 +1,688 @@
+package detect
+
+import (
+	"bank-to-ledger/bankaccount"
+	"bank-to-ledger/charset"
+	cfg "bank-to-ledger/config"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Guessing the format of a bank's csv export.  The guess is a
+// config.Bank together with the alternatives that were considered, so
+// the user can correct it and confirmations can pick among them.
+
+// Delimiters tried, in order of preference on ties
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// Layouts tried when guessing DatePatternFrom, in order of preference
+// on ties (e.g. day-first before month-first for ambiguous dates)
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02.01.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"2.1.2006",
+	"2.1.2006 15:04",
+	"2.1.2006 15:04:05",
+	"2. 1. 2006",
+	"02/01/2006",
+	"01/02/2006",
+	"2/1/2006",
+	"1/2/2006",
+	"2006/01/02",
+	"02-01-2006",
+	"2006.01.02",
+}
+
+var amountRegexp = regexp.MustCompile(`^[+-]?\d+(?:[ ,]\d{3})*(?:[.,]\d+)?$`)
+
+var currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var bankCodeRegexp = regexp.MustCompile(`^\d{4}$`)
+
+const (
+	KindEmpty     = "empty"
+	KindDate      = "date"
+	KindAmount    = "amount"
+	KindNumber    = "number"
+	KindCurrency  = "currency"
+	KindDirection = "direction"
+	KindAccount   = "account"
+	KindText      = "text"
+)
+
+// Header keywords of the columns, compared with the normalized header
+// name (lowercase, single spaces)
+var (
+	dateKeywords      = []string{"date", "datum", "booking", "zaúčtování", "splatnost"}
+	amountKeywords    = []string{"amount", "částka", "castka", "objem", "suma", "betrag", "value", "hodnota"}
+	debitKeywords     = []string{"debit", "výdaj", "vydaj", "odchozí", "odchozi", "withdraw", "out", "paid", "má dáti"}
+	payeeKeywords     = []string{"payee", "counterparty", "protistrana", "název", "nazev", "name", "merchant", "příjemce", "prijemce", "recipient", "beneficiary", "obchodník", "plátce"}
+	noteKeywords      = []string{"zpráva", "zprava", "message", "poznámka", "poznamka", "note", "comment", "reference", "popis", "description", "identifikace", "remittance", "detail"}
+	accountKeywords   = []string{"účet", "ucet", "account", "iban", "protiúčet"}
+	bankCodeKeywords  = []string{"kód banky", "kod banky", "bank code", "sort code"}
+	typeKeywords      = []string{"typ", "type", "druh"}
+	variableKeywords  = []string{"vs", "variabilní", "variabilni", "variable"}
+	constantKeywords  = []string{"ks", "konstantní", "konstantni", "constant"}
+	specificKeywords  = []string{"ss", "specifický", "specificky", "specific"}
+	originalKeywords  = []string{"original", "původní", "puvodni", "zahraniční"}
+	directionKeywords = []string{"direction", "směr", "smer", "debit/credit", "d/c", "cr/dr"}
+)
+
+// Statistics of one csv column
+type Column struct {
+	Index int
+
+	// Header name, empty if the file has no header
+	Name string
+
+	Kind string
+
+	// Layouts parsing all values of a date column, best first
+	DateLayouts []string
+
+	// Whether an amount column has both negative and positive values
+	Signed bool
+
+	// Number of non-empty values
+	Filled int
+
+	// Number of distinct non-empty values
+	Distinct int
+
+	AverageLength float64
+
+	// A few distinct values
+	Samples []string
+}
+
+type Guess struct {
+	Encoding  string
+	Delimiter rune
+
+	Records [][]string
+
+	// Index of the header row, -1 if the file has no header
+	HeaderRow int
+
+	// Index of the first and one past the last transaction row
+	DataStart int
+	DataEnd   int
+
+	Columns []Column
+
+	// The proposed bank config, with ColumnIndices resolved
+	Bank cfg.Bank
+
+	// Alternatives considered for the columns, best first
+	DateCandidates   []int
+	AmountCandidates []int
+	PayeeCandidates  []int
+	NoteCandidates   []int
+
+	// Notes for the user about the guess
+	Warnings []string
+}
+
+// Whether the header name contains one of the keywords.  Short
+// keywords (e.g. `vs`) must match a whole word.
+func hasKeyword(name string, keywords []string) bool {
+	name = cfg.NormalizeColumnName(name)
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '.' || r == '_' || r == '-' || r == '(' || r == ')'
+	})
+
+	for _, keyword := range keywords {
+		if len(keyword) <= 3 {
+			for _, word := range words {
+				if word == keyword {
+					return true
+				}
+			}
+		} else if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Pick the delimiter producing the most rows of the same width
+func DetectDelimiter(content []byte) (rune, [][]string) {
+	var bestDelimiter rune
+	var bestRecords [][]string
+	bestCount, bestWidth := -1, 0
+
+	for _, delimiter := range delimiters {
+		records, err := ParseCsv(content, delimiter, true)
+		if err != nil {
+			continue
+		}
+
+		width, count := ModalWidth(records)
+		if count > bestCount || (count == bestCount && width > bestWidth) {
+			bestDelimiter, bestRecords = delimiter, records
+			bestCount, bestWidth = count, width
+		}
+	}
+
+	return bestDelimiter, bestRecords
+}
+
+func isAmount(value string) bool {
+	return amountRegexp.MatchString(strings.TrimSpace(value))
+}
+
+func isDate(value string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Header rows consist of mostly non-empty cells which are not data
+// values
+func looksLikeHeader(row []string) bool {
+	filled := 0
+	for _, cell := range row {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		if isAmount(cell) || isDate(cell) {
+			return false
+		}
+		filled++
+	}
+
+	return filled*2 > len(row)
+}
+
+func filledCells(row []string) int {
+	filled := 0
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			filled++
+		}
+	}
+
+	return filled
+}
+
+// Find the header row, the first and one past the last transaction
+// row.  Transaction rows have the most common width; rows of other
+// widths before are preamble, after are footer.  Trailing rows with
+// much fewer values than the transactions (totals, balances) are
+// footer too.
+func findRows(records [][]string) (int, int, int) {
+	width, _ := ModalWidth(records)
+
+	first := 0
+	for first < len(records) && len(records[first]) != width {
+		first++
+	}
+
+	last := len(records)
+	for last > first && len(records[last-1]) != width {
+		last--
+	}
+
+	var filled []int
+	for _, row := range records[first:last] {
+		if len(row) == width {
+			filled = append(filled, filledCells(row))
+		}
+	}
+	if len(filled) > 0 {
+		sort.Ints(filled)
+		median := filled[len(filled)/2]
+		for last > first+1 && filledCells(records[last-1])*2 < median {
+			last--
+		}
+	}
+
+	if first < len(records) && looksLikeHeader(records[first]) {
+		return first, first + 1, last
+	}
+
+	return -1, first, last
+}
+
+func analyzeColumn(index int, name string, rows [][]string) Column {
+	column := Column{Index: index, Name: name}
+
+	var values []string
+	distinct := make(map[string]bool)
+	totalLength := 0
+	for _, row := range rows {
+		if index >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[index])
+		if value == "" {
+			continue
+		}
+		values = append(values, value)
+		totalLength += len([]rune(value))
+		if !distinct[value] {
+			distinct[value] = true
+			if len(column.Samples) < 3 {
+				column.Samples = append(column.Samples, value)
+			}
+		}
+	}
+
+	column.Filled = len(values)
+	column.Distinct = len(distinct)
+	if len(values) == 0 {
+		column.Kind = KindEmpty
+		return column
+	}
+	column.AverageLength = float64(totalLength) / float64(len(values))
+
+	all := func(check func(string) bool) bool {
+		for _, value := range values {
+			if !check(value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, layout := range dateLayouts {
+		if all(func(v string) bool { _, err := time.Parse(layout, v); return err == nil }) {
+			column.DateLayouts = append(column.DateLayouts, layout)
+		}
+	}
+
+	switch {
+	case len(column.DateLayouts) > 0 && !all(func(v string) bool { return strings.Trim(v, "0123456789") == "" }):
+		column.Kind = KindDate
+	case all(isAmount):
+		negative := !all(func(v string) bool { return !strings.HasPrefix(v, "-") })
+		positive := !all(func(v string) bool { return strings.HasPrefix(v, "-") })
+		decimals := !all(func(v string) bool { return !strings.ContainsAny(v, ".,") })
+		column.Signed = negative && positive
+
+		if decimals || column.Signed || hasKeyword(name, amountKeywords) {
+			column.Kind = KindAmount
+		} else if hasKeyword(name, accountKeywords) {
+			column.Kind = KindAccount
+		} else {
+			column.Kind = KindNumber
+		}
+	case all(currencyRegexp.MatchString):
+		column.Kind = KindCurrency
+	case all(func(v string) bool { return (cfg.Bank{}).GetDirection(v) != "" }):
+		column.Kind = KindDirection
+	case all(func(v string) bool { _, err := bankaccount.Normalize(v); return err != bankaccount.ErrUnknownFormat }):
+		column.Kind = KindAccount
+	case hasKeyword(name, accountKeywords) && all(func(v string) bool { return strings.Trim(v, "0123456789-/ ") == "" }):
+		column.Kind = KindAccount
+	default:
+		column.Kind = KindText
+	}
+
+	return column
+}
+
+// Order the candidates so those with keywords in the header come
+// first, keeping the original order otherwise
+func preferKeywords(columns []Column, candidates []int, keywords []string) []int {
+	var preferred, rest []int
+	for _, candidate := range candidates {
+		if hasKeyword(columns[candidate].Name, keywords) {
+			preferred = append(preferred, candidate)
+		} else {
+			rest = append(rest, candidate)
+		}
+	}
+
+	return append(preferred, rest...)
+}
+
+// Score of a text column as the payee: keywords, then columns with
+// many distinct, short values
+func payeeScore(column Column, rows int) float64 {
+	score := 0.0
+	if hasKeyword(column.Name, payeeKeywords) {
+		score += 2
+	}
+	if hasKeyword(column.Name, noteKeywords) {
+		score += 0.5
+	}
+	if hasKeyword(column.Name, typeKeywords) {
+		score -= 2
+	}
+	if rows > 0 {
+		score += float64(column.Filled) / float64(rows)
+		score += float64(column.Distinct) / float64(rows)
+	}
+	if column.AverageLength > 40 {
+		score -= 0.5
+	}
+
+	return score
+}
+
+func noteScore(column Column, rows int) float64 {
+	score := 0.0
+	if hasKeyword(column.Name, noteKeywords) {
+		score += 2
+	}
+	if hasKeyword(column.Name, typeKeywords) {
+		score -= 2
+	}
+	if rows > 0 {
+		score += float64(column.Distinct) / float64(rows)
+	}
+
+	return score
+}
+
+func sortByScore(candidates []int, score func(int) float64) {
+	// insertion sort keeps the column order on ties
+	for i := 1; i < len(candidates); i++ {
+		for j := i; j > 0 && score(candidates[j]) > score(candidates[j-1]); j-- {
+			candidates[j], candidates[j-1] = candidates[j-1], candidates[j]
+		}
+	}
+}
+
+// Whether the two unsigned columns are debit and credit: each row has
+// a non-zero value in at most one of them
+func isDebitCreditPair(rows [][]string, a int, b int) bool {
+	nonZero := func(value string) bool {
+		return strings.Trim(strings.TrimSpace(value), "0.,+- ") != ""
+	}
+
+	for _, row := range rows {
+		if nonZero(row[a]) && nonZero(row[b]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *Guess) name(index int) cfg.ColumnName {
+	if g.HeaderRow == -1 {
+		return nil
+	}
+
+	return cfg.ColumnName{g.Records[g.HeaderRow][index]}
+}
+
+func (g *Guess) dataRows() [][]string {
+	var rows [][]string
+	for _, row := range g.Records[g.DataStart:g.DataEnd] {
+		if len(row) == len(g.Columns) {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// Guess the bank config from the content of a csv export
+func Detect(content []byte) *Guess {
+	g := &Guess{Encoding: charset.Detect(content)}
+	content = charset.ToUTF8(content, g.Encoding)
+
+	g.Delimiter, g.Records = DetectDelimiter(content)
+	g.HeaderRow, g.DataStart, g.DataEnd = findRows(g.Records)
+
+	g.Bank = cfg.Bank{ColumnIndices: cfg.NewColumnIndices()}
+	if g.Delimiter != ',' && g.Delimiter != ';' {
+		// , and ; are guessed when converting
+		g.Bank.Delimiter = string(g.Delimiter)
+	}
+	if g.HeaderRow > 0 {
+		g.Bank.SkipRows = g.HeaderRow
+	} else if g.HeaderRow == -1 {
+		g.Bank.SkipRows = g.DataStart
+	}
+	g.Bank.SkipFooterRows = len(g.Records) - g.DataEnd
+
+	if g.DataStart >= len(g.Records) {
+		g.Warnings = append(g.Warnings, "no transaction rows found")
+		return g
+	}
+
+	width := len(g.Records[g.DataStart])
+	if g.HeaderRow != -1 {
+		width = len(g.Records[g.HeaderRow])
+		header := g.Records[g.HeaderRow]
+		identifying := len(header)
+		if identifying > 4 {
+			identifying = 4
+		}
+		g.Bank.IdentifyingColumns = append([]string{}, header[:identifying]...)
+	}
+
+	g.Columns = make([]Column, width)
+	rows := g.dataRows()
+	for i := 0; i < width; i++ {
+		name := ""
+		if g.HeaderRow != -1 {
+			name = g.Records[g.HeaderRow][i]
+		}
+		g.Columns[i] = analyzeColumn(i, name, rows)
+	}
+
+	g.guessColumns(rows)
+
+	return g
+}
+
+func (g *Guess) columnsOfKind(kind string) []int {
+	var indices []int
+	for _, column := range g.Columns {
+		if column.Kind == kind {
+			indices = append(indices, column.Index)
+		}
+	}
+
+	return indices
+}
+
+func (g *Guess) guessColumns(rows [][]string) {
+	ci := &g.Bank.ColumnIndices
+	used := make(map[int]bool)
+
+	// date
+	g.DateCandidates = preferKeywords(g.Columns, g.columnsOfKind(KindDate), dateKeywords)
+	if len(g.DateCandidates) > 0 {
+		g.setDate(g.DateCandidates[0])
+	} else {
+		g.Warnings = append(g.Warnings, "no date column found")
+	}
+
+	// amount
+	var signed, unsigned []int
+	for _, index := range g.columnsOfKind(KindAmount) {
+		if g.Columns[index].Signed {
+			signed = append(signed, index)
+		} else {
+			unsigned = append(unsigned, index)
+		}
+	}
+	signed = preferKeywords(g.Columns, signed, amountKeywords)
+	unsigned = preferKeywords(g.Columns, unsigned, amountKeywords)
+	directions := preferKeywords(g.Columns, g.columnsOfKind(KindDirection), directionKeywords)
+
+	switch {
+	case len(signed) > 0:
+		g.AmountCandidates = signed
+		ci.AmountAccount = signed[0]
+		used[signed[0]] = true
+	case len(directions) > 0 && len(unsigned) > 0:
+		g.AmountCandidates = unsigned
+		ci.AmountAccount = unsigned[0]
+		ci.Direction = directions[0]
+		used[unsigned[0]] = true
+		used[directions[0]] = true
+	default:
+		for i := 0; i < len(unsigned) && ci.AmountDebit == -1; i++ {
+			for j := i + 1; j < len(unsigned); j++ {
+				if isDebitCreditPair(rows, unsigned[i], unsigned[j]) {
+					debit, credit := unsigned[i], unsigned[j]
+					if hasKeyword(g.Columns[credit].Name, debitKeywords) && !hasKeyword(g.Columns[debit].Name, debitKeywords) {
+						debit, credit = credit, debit
+					}
+					ci.AmountDebit, ci.AmountCredit = debit, credit
+					used[debit], used[credit] = true, true
+					break
+				}
+			}
+		}
+
+		if ci.AmountDebit == -1 {
+			g.AmountCandidates = unsigned
+			if len(unsigned) > 0 {
+				ci.AmountAccount = unsigned[0]
+				used[unsigned[0]] = true
+				g.Warnings = append(g.Warnings, "no signed amount column found, amounts may have wrong sign")
+			} else {
+				g.Warnings = append(g.Warnings, "no amount column found")
+			}
+		}
+	}
+
+	// the original amount of card payments in foreign currency
+	for _, index := range append(signed, unsigned...) {
+		if !used[index] && hasKeyword(g.Columns[index].Name, originalKeywords) {
+			ci.AmountReal = index
+			used[index] = true
+			break
+		}
+	}
+
+	// currency
+	currencies := preferKeywords(g.Columns, g.columnsOfKind(KindCurrency), originalKeywords)
+	for _, index := range currencies {
+		if hasKeyword(g.Columns[index].Name, originalKeywords) && ci.CurrencyRaw == -1 {
+			ci.CurrencyRaw = index
+		} else if ci.CurrencyAccount == -1 {
+			ci.CurrencyAccount = index
+		}
+		used[index] = true
+	}
+
+	// counterparty account and payment symbols
+	for _, column := range g.Columns {
+		switch {
+		case used[column.Index] || column.Kind == KindEmpty:
+			continue
+		case column.Kind == KindAccount && ci.ReceiverAccountNumber == -1:
+			ci.ReceiverAccountNumber = column.Index
+		case hasKeyword(column.Name, bankCodeKeywords) && column.Kind != KindText:
+			ci.ReceiverBankCode = column.Index
+		case hasKeyword(column.Name, variableKeywords) && column.Kind != KindText:
+			ci.VariableSymbol = column.Index
+		case hasKeyword(column.Name, constantKeywords) && column.Kind != KindText:
+			ci.ConstantSymbol = column.Index
+		case hasKeyword(column.Name, specificKeywords) && column.Kind != KindText:
+			ci.SpecificSymbol = column.Index
+		case column.Kind == KindNumber && bankCodeRegexp.MatchString(column.Samples[0]) && ci.ReceiverAccountNumber != -1 && ci.ReceiverBankCode == -1 && column.Index == ci.ReceiverAccountNumber+1:
+			ci.ReceiverBankCode = column.Index
+		default:
+			continue
+		}
+		used[column.Index] = true
+	}
+
+	// payee, notes and payment type from the text columns
+	var texts []int
+	for _, index := range g.columnsOfKind(KindText) {
+		if used[index] {
+			continue
+		}
+		if hasKeyword(g.Columns[index].Name, typeKeywords) && ci.PaymentType == -1 &&
+			g.Columns[index].Distinct < g.Columns[index].Filled {
+			ci.PaymentType = index
+			continue
+		}
+		texts = append(texts, index)
+	}
+
+	g.PayeeCandidates = append([]int{}, texts...)
+	sortByScore(g.PayeeCandidates, func(i int) float64 { return payeeScore(g.Columns[i], len(rows)) })
+	if len(g.PayeeCandidates) > 0 {
+		ci.PayeeRaw = g.PayeeCandidates[0]
+	} else {
+		g.Warnings = append(g.Warnings, "no payee column found")
+	}
+
+	g.updateNotes()
+}
+
+func (g *Guess) setDate(index int) {
+	g.Bank.ColumnIndices.DateRaw = index
+	g.Bank.DatePatternFrom = g.Columns[index].DateLayouts[0]
+}
+
+// Note candidates are the text columns other than the payee, the
+// best two are used as the notes
+func (g *Guess) updateNotes() {
+	ci := &g.Bank.ColumnIndices
+	rows := len(g.dataRows())
+
+	g.NoteCandidates = nil
+	for _, index := range g.PayeeCandidates {
+		if index != ci.PayeeRaw && g.Columns[index].Filled > 0 {
+			g.NoteCandidates = append(g.NoteCandidates, index)
+		}
+	}
+	sortByScore(g.NoteCandidates, func(i int) float64 { return noteScore(g.Columns[i], rows) })
+
+	ci.NoteForMe, ci.NoteForReceiver = -1, -1
+	if len(g.NoteCandidates) > 0 {
+		ci.NoteForMe = g.NoteCandidates[0]
+	}
+	if len(g.NoteCandidates) > 1 {
+		ci.NoteForReceiver = g.NoteCandidates[1]
+	}
+}
+
+// Column names of the guessed columns.  Empty if the file has no
+// header, ColumnIndices are used then.
+func (g *Guess) ColumnNames() cfg.ColumnNames {
+	ci := g.Bank.ColumnIndices
+	name := func(index int) cfg.ColumnName {
+		if index == -1 {
+			return nil
+		}
+		return g.name(index)
+	}
+
+	return cfg.ColumnNames{
+		DateRaw:               name(ci.DateRaw),
+		PayeeRaw:              name(ci.PayeeRaw),
+		CurrencyRaw:           name(ci.CurrencyRaw),
+		CurrencyAccount:       name(ci.CurrencyAccount),
+		PaymentType:           name(ci.PaymentType),
+		AmountReal:            name(ci.AmountReal),
+		AmountAccount:         name(ci.AmountAccount),
+		AmountDebit:           name(ci.AmountDebit),
+		AmountCredit:          name(ci.AmountCredit),
+		Direction:             name(ci.Direction),
+		ReceiverAccountNumber: name(ci.ReceiverAccountNumber),
+		ReceiverBankCode:      name(ci.ReceiverBankCode),
+		NoteForMe:             name(ci.NoteForMe),
+		NoteForReceiver:       name(ci.NoteForReceiver),
+		VariableSymbol:        name(ci.VariableSymbol),
+		ConstantSymbol:        name(ci.ConstantSymbol),
+		SpecificSymbol:        name(ci.SpecificSymbol),
+	}
+}
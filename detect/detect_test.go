@@ -0,0 +1,141 @@
+package detect
+
+import (
+	"bank-to-ledger/charset"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const czechExport = `Výpis z účtu;2000145399/0800
+Období;01.01.2024 - 31.01.2024
+
+Datum zaúčtování;Datum valuty;Protiúčet;Kód banky;Název protiúčtu;Výdaj;Příjem;Měna;VS;Zpráva pro příjemce;Poznámka;Typ transakce
+02.01.2024;03.01.2024;2000145399;0800;Tesco Stores;100,50;;CZK;;;nákup potravin;Platba kartou
+05.01.2024;05.01.2024;19-2000145399;0800;ČEZ Prodej;1500,00;;CZK;1234567890;záloha elektřina;;Trvalý příkaz
+10.01.2024;10.01.2024;;;ACME s.r.o.;;45000,00;CZK;;výplata leden;;Příchozí platba
+12.01.2024;13.01.2024;;;Shell Praha;950,00;;CZK;;;tankování;Platba kartou
+Konečný zůstatek;;;;;;;;;;;
+`
+
+const signedExport = `Date,Description,Amount,Currency,Reference
+2024-01-02,TESCO STORES 3021,-100.50,EUR,card 1234
+2024-01-05,ACME LTD,4500.00,EUR,salary
+2024-01-07,SHELL,-50.10,EUR,fuel
+`
+
+func TestDetectDelimiter(t *testing.T) {
+	delimiter, records := DetectDelimiter([]byte(signedExport))
+	assert.Equal(t, ',', delimiter)
+	assert.Equal(t, 4, len(records))
+
+	delimiter, _ = DetectDelimiter([]byte(czechExport))
+	assert.Equal(t, ';', delimiter)
+
+	delimiter, _ = DetectDelimiter([]byte(strings.ReplaceAll(signedExport, ",", "\t")))
+	assert.Equal(t, '\t', delimiter)
+}
+
+func TestDetect_signed_amount(t *testing.T) {
+	g := Detect([]byte(signedExport))
+	ci := g.Bank.ColumnIndices
+
+	assert.Equal(t, charset.UTF8, g.Encoding)
+	assert.Equal(t, 0, g.HeaderRow)
+	assert.Equal(t, []string{"Date", "Description", "Amount", "Currency"}, g.Bank.IdentifyingColumns)
+	assert.Equal(t, "2006-01-02", g.Bank.DatePatternFrom)
+	assert.Equal(t, 0, ci.DateRaw)
+	assert.Equal(t, 1, ci.PayeeRaw)
+	assert.Equal(t, 2, ci.AmountAccount)
+	assert.Equal(t, 3, ci.CurrencyAccount)
+	assert.Equal(t, 4, ci.NoteForMe)
+	assert.Equal(t, -1, ci.AmountDebit)
+}
+
+func TestDetect_debit_credit_with_preamble_and_footer(t *testing.T) {
+	g := Detect(charset.ToUTF8([]byte(czechExport), charset.UTF8))
+	ci := g.Bank.ColumnIndices
+
+	// the empty line is skipped by the csv reader
+	assert.Equal(t, 2, g.HeaderRow)
+	assert.Equal(t, 2, g.Bank.SkipRows)
+	assert.Equal(t, 1, g.Bank.SkipFooterRows)
+	assert.Equal(t, "02.01.2006", g.Bank.DatePatternFrom)
+	assert.Equal(t, 0, ci.DateRaw)
+	assert.Equal(t, []int{0, 1}, g.DateCandidates)
+	assert.Equal(t, 2, ci.ReceiverAccountNumber)
+	assert.Equal(t, 3, ci.ReceiverBankCode)
+	assert.Equal(t, 4, ci.PayeeRaw)
+	assert.Equal(t, 5, ci.AmountDebit)
+	assert.Equal(t, 6, ci.AmountCredit)
+	assert.Equal(t, -1, ci.AmountAccount)
+	assert.Equal(t, 7, ci.CurrencyAccount)
+	assert.Equal(t, 8, ci.VariableSymbol)
+	assert.Equal(t, 11, ci.PaymentType)
+
+	names := g.ColumnNames()
+	assert.Equal(t, "Název protiúčtu", names.PayeeRaw[0])
+}
+
+func TestDetect_without_header(t *testing.T) {
+	lines := strings.Split(signedExport, "\n")
+	g := Detect([]byte(strings.Join(lines[1:], "\n")))
+
+	assert.Equal(t, -1, g.HeaderRow)
+	assert.Equal(t, 0, len(g.Bank.IdentifyingColumns))
+	assert.Equal(t, 2, g.Bank.ColumnIndices.AmountAccount)
+
+	proposal, err := g.Yaml("test")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(proposal), "columnIndices:"))
+}
+
+func TestApply_and_Score(t *testing.T) {
+	g := Detect([]byte(czechExport))
+
+	amount := -1500.0
+	confirmations := []Confirmation{
+		// value date instead of the booking date
+		{Row: 1, Date: "2024-01-03", Note: "potravin"},
+		{Row: 2, Date: "2024-01-05", Payee: "ČEZ Prodej", Amount: &amount, Currency: "CZK"},
+	}
+
+	g.Apply(confirmations)
+	assert.Equal(t, 1, g.Bank.ColumnIndices.DateRaw)
+	assert.Equal(t, 10, g.Bank.ColumnIndices.NoteForMe)
+
+	checks := g.Score(confirmations)
+	assert.Equal(t, 6, len(checks))
+	for _, check := range checks {
+		assert.True(t, check.OK, "%+v", check)
+	}
+
+	checks = g.Score([]Confirmation{{Row: 3, Amount: &amount}, {Row: 100, Payee: "x"}})
+	assert.Equal(t, 2, len(checks))
+	assert.False(t, checks[0].OK)
+	assert.Equal(t, "45000.00", checks[0].Actual)
+	assert.Equal(t, "row", checks[1].Field)
+}
+
+func TestYaml(t *testing.T) {
+	g := Detect([]byte(signedExport))
+	proposal, err := g.Yaml("mybank")
+	assert.Nil(t, err)
+
+	text := string(proposal)
+	assert.True(t, strings.Contains(text, "mybank:"))
+	assert.True(t, strings.Contains(text, "datePatternFrom: '2006-01-02'"))
+	assert.True(t, strings.Contains(text, "payeeRaw: 'Description'"))
+	assert.True(t, strings.Contains(text, "amountAccount: 'Amount'"))
+}
+
+func TestYaml_delimiter(t *testing.T) {
+	g := Detect([]byte(strings.ReplaceAll(signedExport, ",", "|")))
+	proposal, err := g.Yaml("mybank")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(proposal), `delimiter: "|"`), string(proposal))
+
+	// , and ; are guessed when converting
+	assert.Equal(t, "", Detect([]byte(signedExport)).Bank.Delimiter)
+}
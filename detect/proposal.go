@@ -0,0 +1,182 @@
+package detect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+func quoted(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value, Style: yaml.SingleQuotedStyle}
+}
+
+func flowSequence(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, value := range values {
+		node.Content = append(node.Content, quoted(value))
+	}
+
+	return node
+}
+
+func (g *Guess) describe(index int) string {
+	column := g.Columns[index]
+	description := column.Kind
+	if len(column.Samples) > 0 {
+		description += ", e.g. " + strings.Join(column.Samples, " | ")
+	}
+
+	return description
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Guess) columnLabel(index int) string {
+	if g.HeaderRow == -1 || g.Columns[index].Name == "" {
+		return strconv.Itoa(index)
+	}
+
+	return g.Columns[index].Name
+}
+
+func (g *Guess) candidatesComment(label string, candidates []int, chosen ...int) string {
+	var others []string
+	for _, candidate := range candidates {
+		if !contains(chosen, candidate) {
+			others = append(others, g.columnLabel(candidate))
+		}
+	}
+
+	if len(others) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("other %s candidates: %s", label, strings.Join(others, ", "))
+}
+
+func (g *Guess) columnsNode() (string, *yaml.Node) {
+	ci := g.Bank.ColumnIndices
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	fields := []struct {
+		key   string
+		index int
+		head  string
+	}{
+		{"dateRaw", ci.DateRaw, g.candidatesComment("date", g.DateCandidates, ci.DateRaw)},
+		{"payeeRaw", ci.PayeeRaw, g.candidatesComment("payee", g.PayeeCandidates, ci.PayeeRaw)},
+		{"amountAccount", ci.AmountAccount, g.candidatesComment("amount", g.AmountCandidates, ci.AmountAccount)},
+		{"amountDebit", ci.AmountDebit, ""},
+		{"amountCredit", ci.AmountCredit, ""},
+		{"direction", ci.Direction, ""},
+		{"amountReal", ci.AmountReal, ""},
+		{"currencyAccount", ci.CurrencyAccount, ""},
+		{"currencyRaw", ci.CurrencyRaw, ""},
+		{"paymentType", ci.PaymentType, ""},
+		{"receiverAccountNumber", ci.ReceiverAccountNumber, ""},
+		{"receiverBankCode", ci.ReceiverBankCode, ""},
+		{"variableSymbol", ci.VariableSymbol, ""},
+		{"constantSymbol", ci.ConstantSymbol, ""},
+		{"specificSymbol", ci.SpecificSymbol, ""},
+		{"noteForMe", ci.NoteForMe, g.candidatesComment("note", g.NoteCandidates, ci.NoteForMe, ci.NoteForReceiver)},
+		{"noteForReceiver", ci.NoteForReceiver, ""},
+	}
+
+	for _, field := range fields {
+		if field.index == -1 {
+			continue
+		}
+
+		key := scalar(field.key)
+		key.HeadComment = field.head
+
+		var value *yaml.Node
+		if g.HeaderRow == -1 {
+			value = scalar(strconv.Itoa(field.index))
+		} else {
+			value = quoted(g.Columns[field.index].Name)
+		}
+		value.LineComment = g.describe(field.index)
+
+		node.Content = append(node.Content, key, value)
+	}
+
+	if g.HeaderRow == -1 {
+		return "columnIndices", node
+	}
+
+	return "columnNames", node
+}
+
+// The proposed bank config as YAML, to be added to the `banks`
+// section of the config
+func (g *Guess) Yaml(name string) ([]byte, error) {
+	bank := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, value *yaml.Node) {
+		bank.Content = append(bank.Content, scalar(key), value)
+	}
+
+	accountName := scalar("Assets:" + name)
+	accountName.LineComment = "TODO: the ledger account of this bank account"
+	add("accountName", accountName)
+
+	if g.Bank.Delimiter != "" {
+		add("delimiter", &yaml.Node{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: g.Bank.Delimiter})
+	}
+	if len(g.Bank.IdentifyingColumns) > 0 {
+		add("identifyingColumns", flowSequence(g.Bank.IdentifyingColumns))
+	}
+	if g.Bank.SkipRows > 0 {
+		add("skipRows", scalar(strconv.Itoa(g.Bank.SkipRows)))
+	}
+	if g.Bank.SkipFooterRows > 0 {
+		add("skipFooterRows", scalar(strconv.Itoa(g.Bank.SkipFooterRows)))
+	}
+	if g.Bank.DatePatternFrom != "" {
+		layout := quoted(g.Bank.DatePatternFrom)
+		var others []string
+		for _, other := range g.Columns[g.Bank.ColumnIndices.DateRaw].DateLayouts {
+			if other != g.Bank.DatePatternFrom {
+				others = append(others, other)
+			}
+		}
+		if len(others) > 0 {
+			layout.LineComment = "also possible: " + strings.Join(others, ", ")
+		}
+		add("datePatternFrom", layout)
+	}
+
+	key, columns := g.columnsNode()
+	add(key, columns)
+
+	var comments []string
+	comments = append(comments, "Detected encoding: "+g.Encoding)
+	comments = append(comments, fmt.Sprintf("Detected delimiter: %q", g.Delimiter))
+	for _, warning := range g.Warnings {
+		comments = append(comments, "WARNING: "+warning)
+	}
+
+	banksKey := scalar("banks")
+	banksKey.HeadComment = strings.Join(comments, "\n")
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		banksKey,
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{scalar(name), bank}},
+	}}
+
+	return yaml.Marshal(root)
+}
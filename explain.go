@@ -0,0 +1,87 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	t "bank-to-ledger/transaction"
+	"fmt"
+	"regexp"
+)
+
+type ExplainCommand struct {
+	Grep string `long:"grep" description:"Only explain transactions whose raw payee matches this pattern"`
+
+	Args struct {
+		File string `positional-arg-name:"csv" description:"Bank csv export"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Print why the transaction is categorized the way it is and where
+// the deciding definitions are in the config
+func explainTransaction(trans *t.Transaction) {
+	fmt.Printf("%s %s (%.2f %s)\n", trans.DateRaw, trans.PayeeRaw, trans.AmountAccount, trans.CurrencyAccount)
+
+	bank := trans.GetBank()
+	fmt.Printf("    bank:    %s (%s)\n", bank.Name, bank.Source)
+
+	if trans.IsIgnored() {
+		fmt.Printf("    ignored by the ignoredTransactions of the bank\n\n")
+		return
+	}
+
+	payee, exists := trans.GetPayee()
+	if !exists {
+		fmt.Printf("    payee:   unknown, no pattern matched\n")
+		fmt.Printf("    account: %s\n\n", payee.Account)
+		return
+	}
+
+	fmt.Printf("    payee:   %s (%s)\n", payee.Name, payee.Source)
+	if payee.DateRange.IsBounded() {
+		fmt.Printf("    valid:   %s\n", payee.DateRange)
+	}
+	if pattern := trans.GetPattern(); pattern != nil {
+		fmt.Printf("    matched: %s `%s' (%s)\n", pattern.Type, pattern.Value, pattern.Source)
+		if pattern.DateRange.IsBounded() {
+			fmt.Printf("    valid:   %s\n", pattern.DateRange)
+		}
+	}
+	if !payee.HasAccount() {
+		fmt.Printf("    account: none assigned\n\n")
+		return
+	}
+	if rule := trans.GetAccountRule(); rule != nil {
+		fmt.Printf("    account: %s, rule %s (%s)\n\n", trans.GetAccountTo(), rule.Conditions(), rule.Source)
+		return
+	}
+	if key := trans.GetDirectionAccountKey(); key != "" {
+		fmt.Printf("    account: %s, %s of the payee\n\n", trans.GetAccountTo(), key)
+		return
+	}
+	fmt.Printf("    account: %s\n\n", trans.GetAccountTo())
+}
+
+func (c *ExplainCommand) Execute(args []string) error {
+	var grep *regexp.Regexp
+	if c.Grep != "" {
+		var err error
+		grep, err = regexp.Compile("(?i)" + c.Grep)
+		if err != nil {
+			return fmt.Errorf("invalid pattern `%s': %s", c.Grep, err)
+		}
+	}
+
+	config, err := cfg.ReadConfig(options.Config)
+	if err != nil {
+		return err
+	}
+
+	transactions, _ := readCsv(c.Args.File, options, config)
+	for i := range transactions {
+		if grep != nil && !grep.MatchString(transactions[i].PayeeRaw) {
+			continue
+		}
+		explainTransaction(&transactions[i])
+	}
+
+	return nil
+}
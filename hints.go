@@ -0,0 +1,45 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	"bank-to-ledger/suggest"
+	t "bank-to-ledger/transaction"
+	"fmt"
+	"os"
+)
+
+// Number of closest patterns reported for each unknown payee
+const hintsLimit = 3
+
+// Report the existing patterns closest to each unknown raw payee with
+// a pattern that would cover both
+func printHints(transactions []t.Transaction, config cfg.Config) {
+	var seen []string
+
+	for i := range transactions {
+		trans := &transactions[i]
+		if trans.IsIgnored() || Contains(seen, trans.PayeeRaw) {
+			continue
+		}
+		if _, exists := trans.GetPayee(); exists {
+			continue
+		}
+		seen = append(seen, trans.PayeeRaw)
+
+		hints := suggest.NearestPatterns(trans.PayeeRaw, config.Payees, hintsLimit)
+		if len(hints) == 0 {
+			fmt.Fprintf(os.Stderr, "\n\nNo similar pattern for `%s'", trans.PayeeRaw)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "\n\nClosest patterns for `%s':", trans.PayeeRaw)
+		for _, hint := range hints {
+			fmt.Fprintf(os.Stderr, "\n    %s `%s' (%.0f%%)", hint.Payee, hint.Pattern, hint.Similarity*100)
+			if hint.Generalized != "" {
+				fmt.Fprintf(os.Stderr, ", both covered by `%s'", hint.Generalized)
+			}
+		}
+	}
+
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	t "bank-to-ledger/transaction"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	s "strings"
+	"unicode"
+)
+
+// Number of sample transactions shown for each unknown payee
+const interactiveSamples = 3
+
+// Number of completion candidates shown
+const interactiveCandidates = 10
+
+type prompter struct {
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+type unknownPayee struct {
+	payeeRaw     string
+	transactions []t.Transaction
+}
+
+// Group transactions with unknown payee by PayeeRaw, in order of
+// first appearance
+func collectUnknownPayees(transactions []t.Transaction) []*unknownPayee {
+	var unknown []*unknownPayee
+	byRaw := make(map[string]*unknownPayee)
+
+	for _, trans := range transactions {
+		if trans.IsIgnored() {
+			continue
+		}
+
+		if _, exists := trans.GetPayee(); exists {
+			continue
+		}
+
+		up, exists := byRaw[trans.PayeeRaw]
+		if !exists {
+			up = &unknownPayee{payeeRaw: trans.PayeeRaw}
+			byRaw[trans.PayeeRaw] = up
+			unknown = append(unknown, up)
+		}
+		up.transactions = append(up.transactions, trans)
+	}
+
+	return unknown
+}
+
+// Score how well the pattern fuzzy-matches the candidate.  All
+// characters of the pattern must appear in the candidate in order.
+// Consecutive characters and characters at the start of an account
+// segment or word score higher.
+func fuzzyScore(pattern string, candidate string) (int, bool) {
+	pattern = s.ToLower(pattern)
+	lower := []rune(s.ToLower(candidate))
+	score := 0
+	last := -2
+	pos := 0
+
+	for _, pc := range pattern {
+		if unicode.IsSpace(pc) {
+			continue
+		}
+
+		found := false
+		for ; pos < len(lower); pos++ {
+			if lower[pos] != pc {
+				continue
+			}
+
+			score++
+			if pos == last+1 {
+				score += 2
+			}
+			if pos == 0 || lower[pos-1] == ':' || lower[pos-1] == ' ' {
+				score += 3
+			}
+
+			last = pos
+			pos++
+			found = true
+			break
+		}
+
+		if !found {
+			return 0, false
+		}
+	}
+
+	// prefer shorter candidates
+	return score*100 - len(lower), true
+}
+
+func fuzzyFilter(pattern string, candidates []string) []string {
+	type scored struct {
+		candidate string
+		score     int
+	}
+
+	var matches []scored
+	for _, candidate := range candidates {
+		if score, ok := fuzzyScore(pattern, candidate); ok {
+			matches = append(matches, scored{candidate, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.candidate
+	}
+
+	return result
+}
+
+func (p prompter) readLine(prompt string) (string, bool) {
+	fmt.Fprint(p.out, prompt)
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", false
+	}
+
+	return s.TrimSpace(line), true
+}
+
+// Ask for one of the candidates.  The input is fuzzy-matched against
+// the candidates and the best matches are offered by number.  If
+// allowNew is set, entering the same text twice accepts it as a new
+// value.  Empty input cancels the choice.
+func (p prompter) choose(prompt string, candidates []string, allowNew bool) (string, bool) {
+	var shown []string
+	previous := ""
+
+	for {
+		input, ok := p.readLine(prompt)
+		if !ok || input == "" {
+			return "", false
+		}
+
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(shown) {
+			return shown[n-1], true
+		}
+
+		for _, candidate := range candidates {
+			if s.EqualFold(candidate, input) {
+				return candidate, true
+			}
+		}
+
+		if allowNew && input == previous {
+			return input, true
+		}
+		previous = input
+
+		shown = fuzzyFilter(input, candidates)
+		if len(shown) > interactiveCandidates {
+			shown = shown[:interactiveCandidates]
+		}
+
+		if len(shown) == 0 {
+			fmt.Fprintln(p.out, "  no match")
+		}
+		for i, candidate := range shown {
+			fmt.Fprintf(p.out, "  %d) %s\n", i+1, candidate)
+		}
+		if allowNew {
+			fmt.Fprintln(p.out, "  (enter the same text again to use it as is)")
+		}
+	}
+}
+
+func getPayeeNames(config cfg.Config) []string {
+	names := make([]string, 0, len(config.Payees))
+	for name := range config.Payees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Accounts from the accounts hierarchy together with accounts
+// assigned to payees directly
+func getAccountNames(config cfg.Config) []string {
+	accounts := cfg.ListAccounts(config.Accounts)
+	for _, payee := range config.Payees {
+		if payee.Account != "" && !Contains(accounts, payee.Account) {
+			accounts = append(accounts, payee.Account)
+		}
+	}
+	sort.Strings(accounts)
+
+	return accounts
+}
+
+func exactPattern(payeeRaw string) string {
+	return "^" + regexp.QuoteMeta(payeeRaw) + "$"
+}
+
+func (p prompter) printUnknownPayee(up *unknownPayee, index int, total int) {
+	fmt.Fprintf(p.out, "\n[%d/%d] Unknown payee `%s' (%d transactions)\n", index, total, up.payeeRaw, len(up.transactions))
+
+	for i, trans := range up.transactions {
+		if i == interactiveSamples {
+			fmt.Fprintf(p.out, "    ...\n")
+			break
+		}
+
+		note := s.TrimSpace(trans.NoteForMe + " " + trans.NoteForReceiver)
+		fmt.Fprintf(p.out, "    %s  %s  %s\n", trans.FormatDate(), trans.FormatAmountReal(), note)
+	}
+}
+
+func (p prompter) newPayee(up *unknownPayee, config cfg.Config) (*cfg.Payee, bool) {
+	name, ok := p.readLine(fmt.Sprintf("Payee name [%s]: ", up.payeeRaw))
+	if !ok {
+		return nil, false
+	}
+	if name == "" {
+		name = up.payeeRaw
+	}
+
+	if _, exists := config.Payees[name]; exists {
+		fmt.Fprintf(p.out, "Payee `%s' already exists, use assign instead\n", name)
+		return nil, false
+	}
+
+	pattern, ok := p.readLine(fmt.Sprintf("Pattern [%s]: ", exactPattern(up.payeeRaw)))
+	if !ok {
+		return nil, false
+	}
+	if pattern == "" {
+		pattern = exactPattern(up.payeeRaw)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		fmt.Fprintf(p.out, "Invalid pattern: %s\n", err)
+		return nil, false
+	}
+
+	account, ok := p.choose("Account: ", getAccountNames(config), true)
+	if !ok {
+		return nil, false
+	}
+
+	return &cfg.Payee{
+		Name:     name,
+		Account:  account,
+		PayeeRaw: cfg.PayeePatterns{{Value: pattern}},
+	}, true
+}
+
+// Walk through the unknown payees and let the user categorize them.
+// The choices modify the config and bank in place, so they are
+// reflected in the output of this run.
+func categorizeUnknownPayees(transactions []t.Transaction, config cfg.Config, bank *cfg.Bank) {
+	p := prompter{
+		reader: bufio.NewReader(os.Stdin),
+		out:    os.Stderr,
+	}
+
+	p.categorize(transactions, config, bank)
+}
+
+func (p prompter) categorize(transactions []t.Transaction, config cfg.Config, bank *cfg.Bank) {
+	unknown := collectUnknownPayees(transactions)
+
+	for i, up := range unknown {
+		p.printUnknownPayee(up, i+1, len(unknown))
+
+	prompt:
+		for {
+			action, ok := p.readLine("[a]ssign existing payee, [n]ew payee, [i]gnore, [s]kip, [q]uit: ")
+			if !ok {
+				return
+			}
+
+			switch action {
+			case "a":
+				name, ok := p.choose("Payee: ", getPayeeNames(config), false)
+				if !ok {
+					continue
+				}
+				payee := config.Payees[name]
+				payee.PayeeRaw = append(payee.PayeeRaw, cfg.PayeePattern{Value: exactPattern(up.payeeRaw)})
+				break prompt
+			case "n":
+				payee, ok := p.newPayee(up, config)
+				if !ok {
+					continue
+				}
+				config.Payees[payee.Name] = payee
+				break prompt
+			case "i":
+				bank.IgnoredTransactions = append(bank.IgnoredTransactions, cfg.IgnoredTransactions{
+					Matchers: []cfg.Matcher{{PayeeRaw: up.payeeRaw}},
+				})
+				break prompt
+			case "s", "":
+				break prompt
+			case "q":
+				return
+			}
+		}
+	}
+}
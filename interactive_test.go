@@ -0,0 +1,149 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	"bank-to-ledger/transaction"
+	"bufio"
+	"io"
+	s "strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	for _, test := range []struct {
+		pattern   string
+		candidate string
+		matches   bool
+	}{
+		{"groc", "Expenses:Groceries", true},
+		{"EXGR", "Expenses:Groceries", true},
+		{"e g", "Expenses:Groceries", true},
+		{"grocx", "Expenses:Groceries", false},
+		{"sg", "Groceries", false},
+		{"", "Anything", true},
+	} {
+		_, ok := fuzzyScore(test.pattern, test.candidate)
+		assert.Equal(t, test.matches, ok, test.pattern+" "+test.candidate)
+	}
+}
+
+func TestFuzzyFilter_ranking(t *testing.T) {
+	candidates := []string{
+		"Expenses:Transport:Fuel",
+		"Expenses:Food:Restaurants",
+		"Expenses:Food",
+		"Income:Refunds",
+	}
+
+	for _, test := range []struct {
+		pattern  string
+		expected []string
+	}{
+		// segment starts rank before letters inside words, shorter
+		// candidates before longer ones
+		{"food", []string{"Expenses:Food", "Expenses:Food:Restaurants"}},
+		{"ef", []string{"Expenses:Food", "Expenses:Transport:Fuel", "Expenses:Food:Restaurants", "Income:Refunds"}},
+		{"ref", []string{"Income:Refunds"}},
+		{"xyz", []string{}},
+	} {
+		assert.Equal(t, test.expected, fuzzyFilter(test.pattern, candidates), test.pattern)
+	}
+}
+
+func newTestPrompter(input string) prompter {
+	return prompter{
+		reader: bufio.NewReader(s.NewReader(input)),
+		out:    io.Discard,
+	}
+}
+
+func TestPrompterChoose(t *testing.T) {
+	candidates := []string{"Expenses:Food", "Expenses:Fuel", "Income:Salary"}
+
+	for _, test := range []struct {
+		input    string
+		allowNew bool
+		expected string
+		ok       bool
+	}{
+		{"income:salary\n", false, "Income:Salary", true},
+		{"f\n2\n", false, "Expenses:Fuel", true},
+		{"Assets:New\nAssets:New\n", true, "Assets:New", true},
+		{"Assets:New\nAssets:New\n\n", false, "", false},
+		{"\n", false, "", false},
+		{"", false, "", false},
+	} {
+		choice, ok := newTestPrompter(test.input).choose("> ", candidates, test.allowNew)
+		assert.Equal(t, test.ok, ok, test.input)
+		assert.Equal(t, test.expected, choice, test.input)
+	}
+}
+
+func interactiveConfig() (cfg.Config, *cfg.Bank) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Tesco": {Name: "Tesco", Account: "Expenses:Groceries", PayeeRaw: cfg.PayeePatterns{{Value: "^tesco"}}},
+		},
+	}
+
+	return config, &cfg.Bank{}
+}
+
+func interactiveTransactions(config cfg.Config, bank *cfg.Bank, payees ...string) []transaction.Transaction {
+	var transactions []transaction.Transaction
+	for _, payeeRaw := range payees {
+		transactions = append(transactions, transaction.New(map[string]string{"payeeRaw": payeeRaw, "amountAccount": "-10"}, config, bank))
+	}
+
+	return transactions
+}
+
+func TestCollectUnknownPayees(t *testing.T) {
+	config, bank := interactiveConfig()
+	transactions := interactiveTransactions(config, bank, "Albert", "Tesco Praha", "Billa", "Albert")
+
+	unknown := collectUnknownPayees(transactions)
+
+	assert.Equal(t, 2, len(unknown))
+	assert.Equal(t, "Albert", unknown[0].payeeRaw)
+	assert.Equal(t, 2, len(unknown[0].transactions))
+	assert.Equal(t, "Billa", unknown[1].payeeRaw)
+}
+
+func TestCategorize_assign_new_and_ignore(t *testing.T) {
+	config, bank := interactiveConfig()
+	transactions := interactiveTransactions(config, bank, "Tesco Brno 2", "Albert", "Parking")
+
+	// Tesco Brno 2 is known, Albert is assigned to Tesco and Parking
+	// becomes a new payee with the default pattern
+	input := "a\ntesco\nn\nCity Parking\n\nexpenses:groc\n1\n"
+	newTestPrompter(input).categorize(transactions, config, bank)
+
+	assert.Equal(t, []string{"^tesco", "^Albert$"}, patternValues(config.Payees["Tesco"].PayeeRaw))
+	parking := config.Payees["City Parking"]
+	assert.Equal(t, "Expenses:Groceries", parking.Account)
+	assert.Equal(t, []string{"^Parking$"}, patternValues(parking.PayeeRaw))
+	assert.Equal(t, 0, len(bank.IgnoredTransactions))
+}
+
+func TestCategorize_ignore_and_quit(t *testing.T) {
+	config, bank := interactiveConfig()
+	transactions := interactiveTransactions(config, bank, "Albert", "Billa")
+
+	newTestPrompter("i\nq\n").categorize(transactions, config, bank)
+
+	assert.Equal(t, []cfg.IgnoredTransactions{{Matchers: []cfg.Matcher{{PayeeRaw: "Albert"}}}}, bank.IgnoredTransactions)
+	_, exists := config.Payees["Billa"]
+	assert.False(t, exists)
+}
+
+func patternValues(patterns cfg.PayeePatterns) []string {
+	var values []string
+	for _, pattern := range patterns {
+		values = append(values, pattern.Value)
+	}
+
+	return values
+}
@@ -0,0 +1,168 @@
+package journal
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Minimal reader of ledger journals.  Only the parts needed to learn
+// from the history are parsed: transaction headers, postings and
+// metadata comments.  Directives, periodic and automated
+// transactions are skipped.
+
+type Posting struct {
+	Account string
+
+	// Amount in the posting commodity, valid only if HasAmount
+	Amount    float64
+	HasAmount bool
+
+	Commodity string
+}
+
+type Transaction struct {
+	Date   string
+	Status string
+	Payee  string
+
+	// Comment on the header line
+	Note string
+
+	// Metadata from `; Key: value` comments
+	Meta map[string]string
+
+	Postings []Posting
+
+	// Line of the transaction header, starting from 1
+	Line int
+}
+
+var headerRegexp = regexp.MustCompile(`^([0-9][0-9/.\-=]*)\s+(?:([*!])\s*)?(?:\([^)]*\)\s*)?([^;]*?)\s*(?:;\s*(.*))?$`)
+
+var metaRegexp = regexp.MustCompile(`^;\s*([A-Za-z][\w-]*):\s+(.*)$`)
+
+var amountRegexp = regexp.MustCompile(`-?\s*[0-9][0-9 ,]*(?:\.[0-9]+)?`)
+
+var commodityRegexp = regexp.MustCompile(`[^\s0-9.,\-@]+`)
+
+// Parse the amount of a posting, e.g. `-12.50 CZK`, `$12.34` or
+// `10.00 PLN @@ 250.00 Kc`.  Only the amount before the price is
+// used.
+func ParseAmount(text string) (float64, string, bool) {
+	if i := strings.Index(text, "@"); i != -1 {
+		text = text[:i]
+	}
+
+	number := amountRegexp.FindString(text)
+	if number == "" {
+		return 0, "", false
+	}
+
+	commodity := commodityRegexp.FindString(strings.Replace(text, number, " ", 1))
+
+	// the minus can precede the commodity symbol, e.g. -$12.34
+	negative := strings.HasPrefix(strings.TrimSpace(text), "-")
+
+	number = strings.NewReplacer(" ", "", ",", "").Replace(number)
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	if negative && value > 0 {
+		value = -value
+	}
+
+	return value, commodity, true
+}
+
+func parsePosting(line string) Posting {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, ";"); i != -1 {
+		line = strings.TrimSpace(line[:i])
+	}
+
+	// account and amount are separated by a tab or at least two spaces
+	account := line
+	amount := ""
+	if i := strings.IndexAny(line, "\t"); i != -1 {
+		account, amount = line[:i], line[i+1:]
+	}
+	if i := strings.Index(account, "  "); i != -1 {
+		account, amount = account[:i], account[i+2:]+amount
+	}
+
+	posting := Posting{Account: strings.Trim(strings.TrimSpace(account), "()[]")}
+	posting.Amount, posting.Commodity, posting.HasAmount = ParseAmount(amount)
+
+	return posting
+}
+
+func Parse(r io.Reader) ([]Transaction, error) {
+	var transactions []Transaction
+	var current *Transaction
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			current = nil
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+
+		if !indented {
+			current = nil
+			match := headerRegexp.FindStringSubmatch(line)
+			if match == nil {
+				// directive or top-level comment
+				continue
+			}
+
+			transactions = append(transactions, Transaction{
+				Date:   match[1],
+				Status: match[2],
+				Payee:  match[3],
+				Note:   strings.TrimSpace(match[4]),
+				Meta:   make(map[string]string),
+				Line:   lineNumber,
+			})
+			current = &transactions[len(transactions)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ";") {
+			if match := metaRegexp.FindStringSubmatch(trimmed); match != nil {
+				current.Meta[match[1]] = strings.TrimSpace(match[2])
+			}
+			continue
+		}
+
+		current.Postings = append(current.Postings, parsePosting(trimmed))
+	}
+
+	return transactions, scanner.Err()
+}
+
+func ParseFile(fileName string) ([]Transaction, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
@@ -0,0 +1,61 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJournal = `; vim: ft=ledger
+account Expenses:Groceries
+
+2024/01/01 * Tesco ; (^.^) what did you get from there?
+    ; PayeeRaw: TESCO PRAHA 3021
+    ; Location: Prague
+    Expenses:Groceries      100.50 CZK
+    Assets:Bank
+
+2024-01-02 ! (123) Hotel
+    Expenses:Travel:Hotel  10.00 EUR @@ 250.00 Kc
+    Assets:Bank  -250.00 Kc
+
+~ Monthly
+    Expenses:Rent  10000 CZK
+    Assets:Bank
+`
+
+func TestParse(t *testing.T) {
+	transactions, err := Parse(strings.NewReader(testJournal))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(transactions))
+
+	tesco := transactions[0]
+	assert.Equal(t, "2024/01/01", tesco.Date)
+	assert.Equal(t, "*", tesco.Status)
+	assert.Equal(t, "Tesco", tesco.Payee)
+	assert.Equal(t, "(^.^) what did you get from there?", tesco.Note)
+	assert.Equal(t, "TESCO PRAHA 3021", tesco.Meta["PayeeRaw"])
+	assert.Equal(t, 4, tesco.Line)
+	assert.Equal(t, []Posting{
+		{Account: "Expenses:Groceries", Amount: 100.5, HasAmount: true, Commodity: "CZK"},
+		{Account: "Assets:Bank"},
+	}, tesco.Postings)
+
+	hotel := transactions[1]
+	assert.Equal(t, "!", hotel.Status)
+	assert.Equal(t, "Hotel", hotel.Payee)
+	assert.Equal(t, 10.0, hotel.Postings[0].Amount)
+	assert.Equal(t, "EUR", hotel.Postings[0].Commodity)
+	assert.Equal(t, -250.0, hotel.Postings[1].Amount)
+}
+
+func TestParseAmount(t *testing.T) {
+	value, commodity, ok := ParseAmount("-$1,234.50")
+	assert.True(t, ok)
+	assert.Equal(t, -1234.5, value)
+	assert.Equal(t, "$", commodity)
+
+	_, _, ok = ParseAmount("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	"fmt"
+)
+
+type LintCommand struct {
+	Args struct {
+		Files []string `positional-arg-name:"csv" description:"Bank csv exports, used to report currency mappings no transaction uses.  Without them unused currency mappings are not reported"`
+	} `positional-args:"yes"`
+}
+
+func (c *LintCommand) Execute(args []string) error {
+	config, err := cfg.ReadConfig(options.Config)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := config.Lint()
+
+	if len(c.Args.Files) > 0 {
+		used := make(map[string]bool)
+		for _, file := range c.Args.Files {
+			transactions, _ := readCsv(file, options, config)
+			for _, trans := range transactions {
+				used[trans.CurrencyAccount] = true
+				used[trans.CurrencyRaw] = true
+			}
+		}
+		diagnostics = append(diagnostics, config.LintCurrencies(used)...)
+	}
+
+	for _, diagnostic := range diagnostics {
+		fmt.Println(diagnostic)
+	}
+
+	if len(diagnostics) > 0 {
+		return fmt.Errorf("%d problems found in %s", len(diagnostics), options.Config)
+	}
+
+	return nil
+}
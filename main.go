@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bank-to-ledger/charset"
 	cfg "bank-to-ledger/config"
+	"bank-to-ledger/detect"
+	"bank-to-ledger/suggest"
 	t "bank-to-ledger/transaction"
-	"encoding/csv"
 	"fmt"
 	"github.com/jessevdk/go-flags"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	s "strings"
 	//	"github.com/sanity-io/litter"
 )
@@ -24,55 +27,114 @@ type Options struct {
 	HasNoHeader bool `long:"has-no-header" description:"Whether first line of csv is header"`
 
 	BankName string `long:"bank-name" description:"Bank name used to determine csv format."`
+
+	Interactive bool `long:"interactive" description:"Interactively categorize unknown payees before printing the output"`
+
+	Journal []string `long:"journal" description:"Ledger journal to learn account suggestions for unknown payees from, can be repeated"`
+
+	Hints bool `long:"hints" description:"Report the closest existing patterns for unknown payees"`
 }
 
-func readCsv(fileName string, options Options, config cfg.Config) ([]t.Transaction, *cfg.Bank) {
-	file, err := os.Open(fileName)
+// Number of rows of the most common width
+func rowsOfModalWidth(records [][]string) int {
+	_, count := detect.ModalWidth(records)
+	return count
+}
+
+// Read the csv file, converting it to UTF-8 if it is in one of the
+// legacy encodings
+func readContent(fileName string) []byte {
+	content, err := os.ReadFile(fileName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.Comma = ','
+	if encoding := charset.Detect(content); encoding != charset.UTF8 {
+		log.Printf("File %s is not valid UTF-8, reading it as %s", fileName, encoding)
+		content = charset.ToUTF8(content, encoding)
+	}
+
+	return content
+}
+
+// Parse the csv content with , or ; delimiter, whichever works
+func parseRecords(content []byte) ([][]string, error) {
+	records, err := detect.ParseCsv(content, ',', false)
+	if err == nil {
+		return records, nil
+	}
+
+	// try to re-parse with ; delimiter
+	records, err = detect.ParseCsv(content, ';', false)
+	if err == nil {
+		return records, nil
+	}
 
-	records, err := reader.ReadAll()
+	// the file might contain preamble or footer rows of different
+	// width, use the delimiter producing more consistent rows
+	commaRecords, commaErr := detect.ParseCsv(content, ',', true)
+	records, err = detect.ParseCsv(content, ';', true)
+	if err != nil || (commaErr == nil && rowsOfModalWidth(commaRecords) > rowsOfModalWidth(records)) {
+		records, err = commaRecords, commaErr
+	}
+
+	return records, err
+}
+
+// Parse the csv content with the bank's delimiter, if it has one
+func parseBankRecords(content []byte, bank *cfg.Bank) ([][]string, error) {
+	delimiter, exists := bank.GetDelimiter()
+	if !exists {
+		return parseRecords(content)
+	}
+
+	records, err := detect.ParseCsv(content, delimiter, false)
 	if err != nil {
-		// try to re-parse with ; delimiter
-		file.Seek(0, 0)
+		// preamble or footer rows of different width
+		records, err = detect.ParseCsv(content, delimiter, true)
+	}
+
+	return records, err
+}
 
-		reader = csv.NewReader(file)
-		reader.Comma = ';'
+// Find the bank with its own delimiter whose header the csv file has
+func findBankWithDelimiter(content []byte, banks map[string]*cfg.Bank) (*cfg.Bank, [][]string, bool) {
+	for _, bank := range banks {
+		if bank.Delimiter == "" {
+			continue
+		}
 
-		records, err = reader.ReadAll()
+		records, err := parseBankRecords(content, bank)
 		if err != nil {
-			log.Fatal(err)
+			continue
+		}
+		if _, found := bank.FindHeaderRow(records); found {
+			return bank, records, true
 		}
 	}
 
-	hasHeader := false
-	if options.HasHeader {
-		hasHeader = true
-	} else if options.HasNoHeader {
-		hasHeader = false
-	} else {
-		// first row might be a header, guess
-		hasEmpty := false
-		for _, col := range records[0] {
-			if col == "" {
-				hasEmpty = true
-				break
-			}
-		}
+	return nil, nil, false
+}
 
-		if !hasEmpty {
-			log.Println("First row has no empty column, assuming it is the header.")
-			hasHeader = true
+func guessHasHeader(row []string) bool {
+	// first row might be a header, guess
+	for _, col := range row {
+		if col == "" {
+			return false
 		}
 	}
 
+	log.Println("First row has no empty column, assuming it is the header.")
+	return true
+}
+
+func readCsv(fileName string, options Options, config cfg.Config) ([]t.Transaction, *cfg.Bank) {
+	content := readContent(fileName)
+	records, parseErr := parseRecords(content)
+
 	var bank *cfg.Bank
 	var exists bool
+	var err error
 
 	if options.BankName != "" {
 		bank, exists = config.Banks[options.BankName]
@@ -82,46 +144,124 @@ func readCsv(fileName string, options Options, config cfg.Config) ([]t.Transacti
 	} else {
 		// try to get bank config by filename match
 		baseFile := filepath.Base(fileName)
-		for _, b := range config.Banks {
+		var matches []string
+		for name, b := range config.Banks {
 			if b.FileNamePattern != "" {
-				match, _ := regexp.MatchString(b.FileNamePattern, baseFile)
-				if match {
-					bank = b
-					log.Printf("Bank config `%s` determined by file name pattern `%s`", b.Name, b.FileNamePattern)
-					break
+				if match, _ := regexp.MatchString(b.FileNamePattern, baseFile); match {
+					matches = append(matches, name)
 				}
 			}
 		}
+		sort.Strings(matches)
 
-		if !hasHeader {
-			log.Fatal("CVS file does not contain header row and bank name was not provided.  Cannot determine bank configuration.")
+		if len(matches) > 1 {
+			log.Fatalf("File name %s matches the fileNamePattern of banks %s, choose one with --bank-name", baseFile, s.Join(matches, ", "))
+		}
+		if len(matches) == 1 {
+			bank = config.Banks[matches[0]]
+			log.Printf("Bank config `%s` determined by file name pattern `%s`", bank.Name, bank.FileNamePattern)
 		}
 
 		// determine bank automatically
 		if bank == nil {
-			bank, exists = cfg.GetBankConfig(records[0], config.Banks)
+			if parseErr == nil {
+				bank, exists, err = cfg.GetBankConfig(records, config.Banks)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			if !exists {
+				var delimited [][]string
+				if bank, delimited, exists = findBankWithDelimiter(content, config.Banks); exists {
+					records = delimited
+				}
+			}
 			if !exists {
+				if parseErr != nil {
+					log.Fatal(parseErr)
+				}
+				if options.HasNoHeader || !guessHasHeader(records[0]) {
+					log.Fatal("CVS file does not contain header row and bank name was not provided.  Cannot determine bank configuration.")
+				}
 				log.Fatalf("No configured bank matches the cvs file %s", fileName)
 			}
 			log.Printf("Using automatically detected bank %s", bank.Name)
 		}
+	}
+
+	if _, exists := bank.GetDelimiter(); exists || parseErr != nil {
+		records, err = parseBankRecords(content, bank)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
+	headerRow, headerFound := bank.FindHeaderRow(records)
+	if headerRow >= len(records) {
+		log.Fatalf("CSV file %s has only %d rows, bank %s skips %d", fileName, len(records), bank.Name, bank.SkipRows)
 	}
 
+	hasHeader := headerFound
+	if options.HasHeader {
+		hasHeader = true
+	} else if options.HasNoHeader {
+		hasHeader = false
+	} else if !hasHeader {
+		hasHeader = guessHasHeader(records[headerRow])
+	}
+
+	bank.Preamble = cfg.ParsePreamble(records[:headerRow])
+
 	if (bank.ColumnIndices == cfg.ColumnIndices{}) {
 		if !hasHeader {
 			log.Fatal("ColumnIndices not present in bank config and there is not header row to determine them from names.")
 		}
 
-		bank.ColumnIndices = bank.NamesToIndices(records[0])
+		bank.ColumnIndices, err = bank.NamesToIndices(records[headerRow])
+		if err != nil {
+			log.Fatalf("Bank %s: %s", bank.Name, err)
+		}
 	}
 
-	var transactions []t.Transaction
-	for i, record := range records {
-		if i == 0 && hasHeader {
-			continue
+	var header []string
+	if hasHeader {
+		header = records[headerRow]
+	}
+
+	if len(bank.CustomColumns) > 0 {
+		if !hasHeader {
+			log.Fatal("CustomColumns present in bank config and there is not header row to determine them from names.")
+		}
+
+		custom, err := bank.CustomNamesToIndices(header)
+		if err != nil {
+			log.Fatalf("Bank %s: %s", bank.Name, err)
+		}
+
+		if bank.CustomColumnIndices == nil {
+			bank.CustomColumnIndices = make(map[string]int)
+		}
+		for field, index := range custom {
+			bank.CustomColumnIndices[field] = index
 		}
+	}
 
+	if err := bank.ResolveDerivedFields(header); err != nil {
+		log.Fatalf("Bank %s: %s", bank.Name, err)
+	}
+
+	dataStart := headerRow
+	if hasHeader {
+		dataStart++
+	}
+
+	dataEnd := len(records) - bank.SkipFooterRows
+	if dataEnd < dataStart {
+		dataEnd = dataStart
+	}
+
+	var transactions []t.Transaction
+	for _, record := range records[dataStart:dataEnd] {
 		trans := t.FromCsvRecord(record, config, bank)
 		transactions = append(transactions, trans)
 	}
@@ -129,9 +269,14 @@ func readCsv(fileName string, options Options, config cfg.Config) ([]t.Transacti
 	return transactions, bank
 }
 
+var options Options
+
+var parser = flags.NewParser(&options, flags.Default)
+
 func main() {
-	var options Options
-	var parser = flags.NewParser(&options, flags.Default)
+	// without a command, the positional argument is the csv file to
+	// convert
+	parser.SubcommandsOptional = true
 
 	args, err := parser.Parse()
 
@@ -147,12 +292,38 @@ func main() {
 		}
 	}
 
-	config := cfg.LoadConfig(options.Config)
+	if parser.Active != nil {
+		// the command was already executed by the parser
+		return
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "No csv file given")
+		parser.WriteHelp(os.Stderr)
+		os.Exit(1)
+	}
+
+	config, err := cfg.ReadConfig(options.Config)
+	if err != nil {
+		log.Fatal(err)
+	}
 	config.ValidateConfig()
 
 	transactions, bank := readCsv(args[0], options, config)
 	bank.ValidateBankConfig()
 
+	if options.Interactive {
+		categorizeUnknownPayees(transactions, config, bank)
+	}
+
+	if len(options.Journal) > 0 {
+		classifier, err := suggest.LoadOrTrain(options.Journal, config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		addSuggestions(transactions, classifier)
+	}
+
 	buffer := t.TransactionBuffer{}
 	unknownPayees := make([]string, 1)
 
@@ -216,4 +387,8 @@ func main() {
 	}
 
 	fmt.Fprintf(os.Stderr, "\n\n%s", s.Join(unknownPayees, "\n"))
+
+	if options.Hints {
+		printHints(transactions, config)
+	}
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bank-to-ledger/suggest"
+	t "bank-to-ledger/transaction"
+	"fmt"
+)
+
+// Suggestions with lower confidence are not shown
+const minSuggestionConfidence = 0.2
+
+// Add the most likely account as meta to transactions with unknown
+// payee
+func addSuggestions(transactions []t.Transaction, classifier *suggest.Classifier) {
+	for i := range transactions {
+		trans := &transactions[i]
+		if _, exists := trans.GetPayee(); exists {
+			continue
+		}
+
+		tokens := suggest.TransactionTokens(
+			trans.PayeeRaw,
+			[]string{trans.NoteForMe, trans.NoteForReceiver},
+			-trans.AmountReal,
+		)
+
+		suggestions := classifier.Suggest(tokens, 1)
+		if len(suggestions) == 0 || suggestions[0].Confidence < minSuggestionConfidence {
+			continue
+		}
+
+		trans.AddMeta("SuggestedAccount", fmt.Sprintf(
+			"%s (%s, %.0f%%)",
+			suggestions[0].Account,
+			suggestions[0].Payee,
+			suggestions[0].Confidence*100,
+		))
+	}
+}
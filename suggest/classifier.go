@@ -0,0 +1,256 @@
+package suggest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Naive Bayes classifier suggesting the payee (and through it the
+// account) from the tokens of the raw payee, notes and the amount.
+
+type Suggestion struct {
+	Payee   string
+	Account string
+
+	// Probability of the account, between 0 and 1
+	Confidence float64
+}
+
+type payeeClass struct {
+	Account string
+
+	// Number of training documents
+	Documents int
+
+	TokenCounts map[string]int
+
+	// Sum of TokenCounts
+	Tokens int
+
+	// How many times each account was used for this payee.  The most
+	// frequent one is stored in Account.
+	Accounts map[string]int
+}
+
+type Classifier struct {
+	Payees map[string]*payeeClass
+
+	Documents int
+
+	Vocabulary map[string]bool
+}
+
+func NewClassifier() *Classifier {
+	return &Classifier{
+		Payees:     make(map[string]*payeeClass),
+		Vocabulary: make(map[string]bool),
+	}
+}
+
+// Split the text into lowercase tokens.  Numbers are dropped, they are
+// usually dates, card numbers or store numbers which do not
+// generalize.
+func Tokenize(text string) []string {
+	var tokens []string
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || strings.IndexFunc(field, unicode.IsLetter) == -1 {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+
+	return tokens
+}
+
+// Token representing the sign and order of magnitude of the amount,
+// e.g. `amount:+100` for amounts between 100 and 999
+func AmountToken(amount float64) string {
+	if amount == 0 {
+		return "amount:0"
+	}
+
+	sign := "+"
+	if amount < 0 {
+		sign = "-"
+	}
+
+	magnitude := math.Pow(10, math.Floor(math.Log10(math.Abs(amount))))
+	return fmt.Sprintf("amount:%s%.0f", sign, magnitude)
+}
+
+// Extract the lowercase literal parts of a regular expression, e.g.
+// `tesco praha brno` from `^tesco (praha|brno)`.  Patterns are
+// matched case-insensitively, so the case is not significant.
+func PatternLiterals(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return strings.ToLower(pattern)
+	}
+
+	var literals []string
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		switch re.Op {
+		case syntax.OpLiteral:
+			literals = append(literals, string(re.Rune))
+		default:
+			for _, sub := range re.Sub {
+				walk(sub)
+			}
+		}
+	}
+	walk(re.Simplify())
+
+	return strings.ToLower(strings.Join(strings.Fields(strings.Join(literals, " ")), " "))
+}
+
+func (c *Classifier) Train(payee string, account string, tokens []string) {
+	class, exists := c.Payees[payee]
+	if !exists {
+		class = &payeeClass{
+			TokenCounts: make(map[string]int),
+			Accounts:    make(map[string]int),
+		}
+		c.Payees[payee] = class
+	}
+
+	class.Documents++
+	c.Documents++
+
+	for _, token := range tokens {
+		class.TokenCounts[token]++
+		class.Tokens++
+		c.Vocabulary[token] = true
+	}
+
+	if account != "" {
+		class.Accounts[account]++
+		if class.Account == "" || class.Accounts[account] > class.Accounts[class.Account] {
+			class.Account = account
+		}
+	}
+}
+
+// Suggest the most likely accounts, best first.  The probabilities of
+// payees sharing an account are summed.  The payee of the suggestion
+// is the most likely payee with that account.  Nothing is suggested
+// if none of the text tokens was seen in training, the amount alone
+// is not enough.
+func (c *Classifier) Suggest(tokens []string, limit int) []Suggestion {
+	known := false
+	for _, token := range tokens {
+		if c.Vocabulary[token] && !strings.HasPrefix(token, "amount:") {
+			known = true
+			break
+		}
+	}
+
+	if c.Documents == 0 || !known {
+		return nil
+	}
+
+	type scored struct {
+		payee string
+		score float64
+	}
+
+	var scores []scored
+	best := math.Inf(-1)
+	vocabulary := float64(len(c.Vocabulary))
+
+	for payee, class := range c.Payees {
+		if class.Account == "" {
+			continue
+		}
+
+		score := math.Log(float64(class.Documents) / float64(c.Documents))
+		for _, token := range tokens {
+			if !c.Vocabulary[token] {
+				continue
+			}
+			// Laplace smoothing
+			score += math.Log((float64(class.TokenCounts[token]) + 1) / (float64(class.Tokens) + vocabulary))
+		}
+
+		scores = append(scores, scored{payee, score})
+		if score > best {
+			best = score
+		}
+	}
+
+	// normalize the log probabilities
+	total := 0.0
+	for i := range scores {
+		scores[i].score = math.Exp(scores[i].score - best)
+		total += scores[i].score
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score == scores[j].score {
+			return scores[i].payee < scores[j].payee
+		}
+		return scores[i].score > scores[j].score
+	})
+
+	byAccount := make(map[string]*Suggestion)
+	var suggestions []*Suggestion
+	for _, s := range scores {
+		account := c.Payees[s.payee].Account
+		suggestion, exists := byAccount[account]
+		if !exists {
+			suggestion = &Suggestion{Payee: s.payee, Account: account}
+			byAccount[account] = suggestion
+			suggestions = append(suggestions, suggestion)
+		}
+		suggestion.Confidence += s.score / total
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Confidence > suggestions[j].Confidence
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	result := make([]Suggestion, len(suggestions))
+	for i, s := range suggestions {
+		result[i] = *s
+	}
+
+	return result
+}
+
+func (c *Classifier) Save(fileName string) error {
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, content, 0644)
+}
+
+func LoadClassifier(fileName string) (*Classifier, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewClassifier()
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
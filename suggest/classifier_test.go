@@ -0,0 +1,85 @@
+package suggest
+
+import (
+	"bank-to-ledger/journal"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const trainingJournal = `
+2024/01/01 * Tesco
+    ; PayeeRaw: TESCO PRAHA 3021
+    Expenses:Groceries      100.50 CZK
+    Assets:Bank
+
+2024/01/02 * Albert
+    ; PayeeRaw: ALBERT HYPERMARKET
+    Expenses:Groceries      250.00 CZK
+    Assets:Bank
+
+2024/01/03 * Shell
+    ; PayeeRaw: SHELL 1234 PRAHA
+    Expenses:Car:Fuel      1500.00 CZK
+    Assets:Bank
+
+2024/01/04 * Employer
+    ; PayeeRaw: ACME S.R.O.
+    Assets:Bank      50000.00 CZK
+    Income:Salary
+`
+
+func getTrainedClassifier(t *testing.T) *Classifier {
+	transactions, err := journal.Parse(strings.NewReader(trainingJournal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClassifier()
+	TrainFromJournal(c, transactions)
+
+	return c
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"tesco", "stores", "praha"}, Tokenize("TESCO STORES 3021, Praha 1"))
+}
+
+func TestAmountToken(t *testing.T) {
+	assert.Equal(t, "amount:+100", AmountToken(250))
+	assert.Equal(t, "amount:-1000", AmountToken(-1500))
+}
+
+func TestPatternLiterals(t *testing.T) {
+	assert.Equal(t, "tesco praha brno", PatternLiterals("^tesco (praha|brno)"))
+	assert.Equal(t, "shell", PatternLiterals("(?i)^shell.*$"))
+}
+
+func TestSuggest(t *testing.T) {
+	c := getTrainedClassifier(t)
+
+	suggestions := c.Suggest(TransactionTokens("TESCO STORES 3021", nil, 320), 3)
+
+	assert.Equal(t, "Expenses:Groceries", suggestions[0].Account)
+	assert.Equal(t, "Tesco", suggestions[0].Payee)
+	assert.True(t, suggestions[0].Confidence > 0.5)
+}
+
+func TestClassifier_save_and_load(t *testing.T) {
+	c := getTrainedClassifier(t)
+	fileName := filepath.Join(t.TempDir(), "model.json")
+
+	assert.Nil(t, c.Save(fileName))
+
+	loaded, err := LoadClassifier(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, c, loaded)
+}
+
+func TestSuggest_unknown_tokens(t *testing.T) {
+	c := getTrainedClassifier(t)
+
+	assert.Nil(t, c.Suggest(TransactionTokens("NEW SHOP", nil, 320), 3))
+}
@@ -0,0 +1,256 @@
+package suggest
+
+import (
+	cfg "bank-to-ledger/config"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Patterns less similar than this are not reported
+const minHintSimilarity = 0.3
+
+// Existing payee pattern close to an unmatched raw payee
+type Hint struct {
+	Payee   string
+	Pattern string
+
+	// Similarity of the raw payee and the literal parts of the
+	// pattern, between 0 and 1
+	Similarity float64
+
+	// Pattern matching both the strings matched by Pattern and the
+	// raw payee, empty if none was found
+	Generalized string
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
+
+// Similarity of two strings as the better of token overlap (Jaccard
+// index) and normalized edit distance
+func Similarity(a string, b string) float64 {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+
+	tokensA := Tokenize(a)
+	tokensB := Tokenize(b)
+	setA := make(map[string]bool)
+	for _, token := range tokensA {
+		setA[token] = true
+	}
+
+	union := len(setA)
+	common := 0
+	seen := make(map[string]bool)
+	for _, token := range tokensB {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		if setA[token] {
+			common++
+		} else {
+			union++
+		}
+	}
+
+	jaccard := 0.0
+	if union > 0 {
+		jaccard = float64(common) / float64(union)
+	}
+
+	runesA, runesB := []rune(a), []rune(b)
+	longest := len(runesA)
+	if len(runesB) > longest {
+		longest = len(runesB)
+	}
+	if longest == 0 {
+		return jaccard
+	}
+
+	edit := 1 - float64(levenshtein(runesA, runesB))/float64(longest)
+	if edit > jaccard {
+		return edit
+	}
+
+	return jaccard
+}
+
+// Longest common substring of two strings, in runes
+func longestCommonSubstring(a []rune, b []rune) string {
+	bestLength, bestEnd := 0, 0
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				current[j] = previous[j-1] + 1
+				if current[j] > bestLength {
+					bestLength, bestEnd = current[j], i
+				}
+			} else {
+				current[j] = 0
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return string(a[bestEnd-bestLength : bestEnd])
+}
+
+func quoteTokens(tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, token := range tokens {
+		quoted[i] = regexp.QuoteMeta(token)
+	}
+
+	return strings.Join(quoted, `\W+`)
+}
+
+// Propose a pattern covering both the strings the pattern matches and
+// the raw payee.  The common leading words are kept anchored if the
+// pattern is anchored, otherwise the longest common run of words or
+// characters is used.  Returns empty string if there is nothing in
+// common.
+func GeneralizePattern(pattern string, payeeRaw string) string {
+	covered := append(cfg.PatternExamples(pattern), payeeRaw)
+
+	// \b only knows ASCII letters, so it fails next to e.g. `Ž', and
+	// the pattern may continue with word characters after the common
+	// words.  The candidates without it are used then.
+	for _, candidate := range generalizedCandidates(pattern, payeeRaw) {
+		if coversAll(candidate, covered) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+func coversAll(pattern string, values []string) bool {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return false
+	}
+
+	for _, value := range values {
+		if !re.MatchString(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Generalized patterns, the most specific first
+func generalizedCandidates(pattern string, payeeRaw string) []string {
+	literal := strings.Fields(PatternLiterals(pattern))
+	raw := strings.Fields(strings.ToLower(payeeRaw))
+
+	prefix := 0
+	for prefix < len(literal) && prefix < len(raw) && literal[prefix] == raw[prefix] {
+		prefix++
+	}
+
+	if prefix > 0 && strings.HasPrefix(pattern, "^") {
+		quoted := quoteTokens(literal[:prefix])
+		return []string{"^" + quoted + `\b`, "^" + quoted}
+	}
+
+	// longest common run of words
+	bestStart, bestLength := 0, 0
+	for i := range literal {
+		for j := range raw {
+			length := 0
+			for i+length < len(literal) && j+length < len(raw) && literal[i+length] == raw[j+length] {
+				length++
+			}
+			if length > bestLength {
+				bestStart, bestLength = i, length
+			}
+		}
+	}
+
+	if bestLength > 0 {
+		quoted := quoteTokens(literal[bestStart : bestStart+bestLength])
+		return []string{`\b` + quoted + `\b`, quoted}
+	}
+
+	common := strings.TrimSpace(longestCommonSubstring([]rune(strings.Join(literal, " ")), []rune(strings.Join(raw, " "))))
+	if len([]rune(common)) >= 4 {
+		return []string{regexp.QuoteMeta(common)}
+	}
+
+	return nil
+}
+
+// Find the existing PayeeRaw patterns closest to the raw payee, best
+// first
+func NearestPatterns(payeeRaw string, payees map[string]*cfg.Payee, limit int) []Hint {
+	var hints []Hint
+
+	for name, payee := range payees {
+		for _, pattern := range payee.PayeeRaw {
+			similarity := Similarity(PatternLiterals(pattern.Value), payeeRaw)
+			if nameSimilarity := Similarity(name, payeeRaw); nameSimilarity > similarity {
+				similarity = nameSimilarity
+			}
+
+			if similarity < minHintSimilarity {
+				continue
+			}
+
+			hints = append(hints, Hint{
+				Payee:       name,
+				Pattern:     pattern.Value,
+				Similarity:  similarity,
+				Generalized: GeneralizePattern(pattern.Value, payeeRaw),
+			})
+		}
+	}
+
+	sort.Slice(hints, func(i, j int) bool {
+		if hints[i].Similarity == hints[j].Similarity {
+			return hints[i].Payee+hints[i].Pattern < hints[j].Payee+hints[j].Pattern
+		}
+		return hints[i].Similarity > hints[j].Similarity
+	})
+
+	if len(hints) > limit {
+		hints = hints[:limit]
+	}
+
+	return hints
+}
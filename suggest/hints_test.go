@@ -0,0 +1,63 @@
+package suggest
+
+import (
+	cfg "bank-to-ledger/config"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("Tesco Praha", "TESCO PRAHA"))
+	assert.Equal(t, 0.0, Similarity("", "abc"))
+	assert.True(t, Similarity("tesco praha", "tesco stores") > Similarity("tesco praha", "shell"))
+	// typo
+	assert.True(t, Similarity("alza.cz", "alsa.cz") > 0.8)
+}
+
+func TestGeneralizePattern(t *testing.T) {
+	generalized := GeneralizePattern("^tesco praha", "TESCO STORES 3021")
+	assert.Equal(t, `^tesco\b`, generalized)
+
+	generalized = GeneralizePattern("shell praha", "CCS SHELL 1234")
+	assert.Equal(t, `\bshell\b`, generalized)
+
+	generalized = GeneralizePattern("^www.alza.cz", "ALZA CZ ONLINE")
+	re := regexp.MustCompile("(?i)" + generalized)
+	assert.True(t, re.MatchString("www.alza.cz"))
+	assert.True(t, re.MatchString("ALZA CZ ONLINE"))
+
+	assert.Equal(t, "", GeneralizePattern("^tesco", "SHELL"))
+}
+
+func TestGeneralizePattern_non_ascii(t *testing.T) {
+	// \b does not match between `É' or `Ž' and a space
+	assert.Equal(t, `^café`, GeneralizePattern("^café praha", "CAFÉ BRNO"))
+	assert.Equal(t, `žabka`, GeneralizePattern("žabka praha", "NÁKUP ŽABKA"))
+	assert.Equal(t, `^tesco\b`, GeneralizePattern("^tesco praha", "Tesco ČB"))
+}
+
+func TestGeneralizePattern_covers_old_pattern(t *testing.T) {
+	generalized := GeneralizePattern(`^tesco\d+praha`, "TESCO STORES")
+	assert.Equal(t, `^tesco`, generalized)
+
+	re := regexp.MustCompile("(?i)" + generalized)
+	assert.True(t, re.MatchString("tesco123praha"))
+	assert.True(t, re.MatchString("TESCO STORES"))
+}
+
+func TestNearestPatterns(t *testing.T) {
+	payees := map[string]*cfg.Payee{
+		"Tesco": {PayeeRaw: cfg.PayeePatterns{{Value: "^tesco praha"}}},
+		"Shell": {PayeeRaw: cfg.PayeePatterns{{Value: "shell"}}},
+	}
+
+	hints := NearestPatterns("TESCO STORES 3021", payees, 3)
+	assert.Equal(t, 1, len(hints))
+	assert.Equal(t, "Tesco", hints[0].Payee)
+	assert.Equal(t, "^tesco praha", hints[0].Pattern)
+	assert.Equal(t, `^tesco\b`, hints[0].Generalized)
+
+	assert.Equal(t, 0, len(NearestPatterns("LIDL", payees, 3)))
+}
@@ -0,0 +1,148 @@
+package suggest
+
+import (
+	"bank-to-ledger/journal"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Generalized patterns with shorter literal part are too broad to be
+// useful, e.g. `\bcz\b`
+const minGeneralizedLiteral = 4
+
+// Payee inferred from a journal
+type InferredPayee struct {
+	Name string
+
+	// Most frequently used account
+	Account string
+
+	// PayeeRaw metadata of the transactions, sorted
+	Raw []string
+}
+
+type patternGroup struct {
+	pattern string
+	raws    []string
+}
+
+func matchesAll(pattern string, raws []string) bool {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return false
+	}
+
+	for _, raw := range raws {
+		if !re.MatchString(raw) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Infer PayeeRaw patterns covering the raw payees.  Each raw payee
+// starts as an exact pattern; patterns are generalized to cover
+// similar raw payees, e.g. `^tesco\b` for `TESCO PRAHA 3021` and
+// `TESCO BRNO 12`.
+func InferPatterns(raws []string) []string {
+	var groups []*patternGroup
+
+	for _, raw := range raws {
+		covered := false
+		for _, group := range groups {
+			if matchesAll(group.pattern, []string{raw}) {
+				group.raws = append(group.raws, raw)
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+
+		for _, group := range groups {
+			generalized := GeneralizePattern(group.pattern, raw)
+			if len(PatternLiterals(generalized)) < minGeneralizedLiteral {
+				continue
+			}
+
+			raws := append(append([]string{}, group.raws...), raw)
+			if matchesAll(generalized, raws) {
+				group.pattern = generalized
+				group.raws = raws
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+
+		groups = append(groups, &patternGroup{
+			pattern: "^" + regexp.QuoteMeta(raw) + "$",
+			raws:    []string{raw},
+		})
+	}
+
+	patterns := make([]string, len(groups))
+	for i, group := range groups {
+		patterns[i] = group.pattern
+	}
+
+	return patterns
+}
+
+// Infer the payees, their accounts and raw payees from journal
+// transactions.  As in TrainFromJournal, the first posting is taken
+// as the categorized one.
+func InferPayees(transactions []journal.Transaction) []InferredPayee {
+	accounts := make(map[string]map[string]int)
+	raws := make(map[string]map[string]bool)
+
+	for _, trans := range transactions {
+		if len(trans.Postings) == 0 || trans.Payee == "" || strings.HasPrefix(trans.Payee, "Unknown payee") {
+			continue
+		}
+
+		account := trans.Postings[0].Account
+		if strings.HasPrefix(account, "Unknown:") {
+			continue
+		}
+
+		if _, exists := accounts[trans.Payee]; !exists {
+			accounts[trans.Payee] = make(map[string]int)
+			raws[trans.Payee] = make(map[string]bool)
+		}
+		accounts[trans.Payee][account]++
+
+		if raw := trans.Meta["PayeeRaw"]; raw != "" {
+			raws[trans.Payee][raw] = true
+		}
+	}
+
+	var payees []InferredPayee
+	for name, counts := range accounts {
+		payee := InferredPayee{Name: name}
+		for account, count := range counts {
+			if payee.Account == "" || count > counts[payee.Account] ||
+				(count == counts[payee.Account] && account < payee.Account) {
+				payee.Account = account
+			}
+		}
+
+		for raw := range raws[name] {
+			payee.Raw = append(payee.Raw, raw)
+		}
+		sort.Strings(payee.Raw)
+
+		payees = append(payees, payee)
+	}
+
+	sort.Slice(payees, func(i, j int) bool {
+		return payees[i].Name < payees[j].Name
+	})
+
+	return payees
+}
@@ -0,0 +1,52 @@
+package suggest
+
+import (
+	"bank-to-ledger/journal"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferPatterns(t *testing.T) {
+	patterns := InferPatterns([]string{"TESCO PRAHA 3021", "TESCO BRNO 12", "ALBERT"})
+	assert.Equal(t, []string{`^tesco\b`, `^ALBERT$`}, patterns)
+
+	patterns = InferPatterns([]string{"A+B S.R.O."})
+	assert.Equal(t, []string{`^A\+B S\.R\.O\.$`}, patterns)
+
+	// the common part is too short
+	patterns = InferPatterns([]string{"CZ ONE", "CZ TWO"})
+	assert.Equal(t, []string{`^CZ ONE$`, `^CZ TWO$`}, patterns)
+
+	assert.Equal(t, 0, len(InferPatterns(nil)))
+}
+
+func TestInferPayees(t *testing.T) {
+	transactions, err := journal.Parse(strings.NewReader(trainingJournal + `
+2024/01/05 * Tesco
+    ; PayeeRaw: TESCO BRNO 12
+    Expenses:Groceries      80.00 CZK
+    Assets:Bank
+
+2024/01/06 * Tesco
+    Expenses:Household      80.00 CZK
+    Assets:Bank
+
+2024/01/07 * Unknown payee ;FOO
+    Unknown:Account      80.00 CZK
+    Assets:Bank
+`))
+	assert.Nil(t, err)
+
+	payees := InferPayees(transactions)
+	assert.Equal(t, 4, len(payees))
+
+	assert.Equal(t, InferredPayee{
+		Name:    "Tesco",
+		Account: "Expenses:Groceries",
+		Raw:     []string{"TESCO BRNO 12", "TESCO PRAHA 3021"},
+	}, payees[3])
+	assert.Equal(t, "Employer", payees[1].Name)
+	assert.Equal(t, "Assets:Bank", payees[1].Account)
+}
@@ -0,0 +1,118 @@
+package suggest
+
+import (
+	cfg "bank-to-ledger/config"
+	"bank-to-ledger/journal"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tokens describing a bank transaction.  The amount is the amount of
+// the categorized posting, i.e. positive for expenses.
+func TransactionTokens(payeeRaw string, notes []string, amount float64) []string {
+	tokens := Tokenize(payeeRaw + " " + strings.Join(notes, " "))
+	return append(tokens, AmountToken(amount))
+}
+
+// Learn from categorized journal transactions.  The first posting is
+// taken as the categorized one, which is the order this tool
+// generates.
+func TrainFromJournal(c *Classifier, transactions []journal.Transaction) {
+	for _, trans := range transactions {
+		if len(trans.Postings) == 0 || strings.HasPrefix(trans.Payee, "Unknown payee") {
+			continue
+		}
+
+		posting := trans.Postings[0]
+		if strings.HasPrefix(posting.Account, "Unknown:") {
+			continue
+		}
+
+		amount := posting.Amount
+		if !posting.HasAmount {
+			amount = 0
+			for _, other := range trans.Postings[1:] {
+				amount -= other.Amount
+			}
+		}
+
+		tokens := TransactionTokens(trans.Payee, []string{trans.Meta["PayeeRaw"]}, amount)
+		c.Train(trans.Payee, posting.Account, tokens)
+	}
+}
+
+// Learn from the payees config.  The tokens are the payee name and the
+// literal parts of the PayeeRaw patterns.
+func TrainFromPayees(c *Classifier, payees map[string]*cfg.Payee) {
+	for name, payee := range payees {
+		if payee.Account == "" {
+			continue
+		}
+
+		texts := []string{name}
+		for _, pattern := range payee.PayeeRaw {
+			texts = append(texts, PatternLiterals(pattern.Value))
+		}
+
+		c.Train(name, payee.Account, Tokenize(strings.Join(texts, " ")))
+	}
+}
+
+// Cache file name derived from the paths, sizes and modification times
+// of the training files, so the model is retrained when any of them
+// changes
+func cacheFileName(files []string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		abs, _ := filepath.Abs(file)
+		fmt.Fprintf(hash, "%s %d %d\n", abs, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return filepath.Join(cacheDir, "bank-to-ledger", fmt.Sprintf("suggest-%x.json", hash.Sum(nil)[:8])), nil
+}
+
+// Load the classifier trained on the journals and the config's payees
+// from the local cache, or train it and store it in the cache.
+func LoadOrTrain(journals []string, config cfg.Config) (*Classifier, error) {
+	cacheFile, cacheErr := cacheFileName(append(append([]string{}, config.Files()...), journals...))
+	if cacheErr == nil {
+		if c, err := LoadClassifier(cacheFile); err == nil {
+			return c, nil
+		}
+	}
+
+	c := NewClassifier()
+	for _, fileName := range journals {
+		transactions, err := journal.ParseFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		TrainFromJournal(c, transactions)
+	}
+	TrainFromPayees(c, config.Payees)
+
+	if cacheErr == nil {
+		err := os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		if err == nil {
+			err = c.Save(cacheFile)
+		}
+		if err != nil {
+			log.Printf("Could not cache the suggestion model: %s", err)
+		}
+	}
+
+	return c, nil
+}
@@ -12,6 +12,16 @@ type TextTemplateBank struct {
 	AccountName    string
 	FeeAccountName string
 	Templates      map[string]string
+	// Key-value pairs from the rows preceding the csv header
+	Preamble map[string]string
+}
+
+// Bank from the embedded bank code directory
+type TextTemplateBankInfo struct {
+	Country string
+	Code    string
+	BIC     string
+	Name    string
 }
 
 type TextTemplateTransaction struct {
@@ -33,11 +43,22 @@ type TextTemplateTransaction struct {
 
 	NoteForMe       string
 	NoteForReceiver string
+
+	VariableSymbol string
+	ConstantSymbol string
+	SpecificSymbol string
+
+	// Bank of the receiver account, empty if not known
+	CounterpartyBank TextTemplateBankInfo
+
+	Custom map[string]string
 }
 
 type TextTemplatePayee struct {
 	Name    string
 	Account string
+	// Groups the payee belongs to
+	Groups []string
 }
 
 type TextTemplateParams struct {
@@ -0,0 +1,46 @@
+package main
+
+import (
+	cfg "bank-to-ledger/config"
+	t "bank-to-ledger/transaction"
+	"fmt"
+)
+
+type TestConfigCommand struct{}
+
+func (c *TestConfigCommand) Execute(args []string) error {
+	config, err := cfg.ReadConfig(options.Config)
+	if err != nil {
+		return err
+	}
+
+	if len(config.Tests) == 0 {
+		fmt.Printf("No tests found in %s\n", options.Config)
+		return nil
+	}
+
+	failed := 0
+	for _, test := range config.Tests {
+		differences, err := t.RunConfigTest(test, config)
+		if err == nil && len(differences) == 0 {
+			fmt.Printf("PASS %s %s\n", test.Source, test.PayeeRaw)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %s %s\n", test.Source, test.PayeeRaw)
+		if err != nil {
+			fmt.Printf("    %s\n", err)
+		}
+		for _, difference := range differences {
+			fmt.Printf("    %s\n", difference)
+		}
+	}
+
+	fmt.Printf("%d of %d tests passed\n", len(config.Tests)-failed, len(config.Tests))
+	if failed > 0 {
+		return fmt.Errorf("%d tests failed", failed)
+	}
+
+	return nil
+}
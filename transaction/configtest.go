@@ -0,0 +1,136 @@
+package transaction
+
+import (
+	cfg "bank-to-ledger/config"
+	"fmt"
+	"strconv"
+)
+
+// Transaction built from field values by their config names, see
+// config.DerivedField
+func New(fields map[string]string, config cfg.Config, bank *cfg.Bank) Transaction {
+	trans := Transaction{
+		Custom: make(map[string]string),
+		config: config,
+		bank:   bank,
+	}
+
+	for field, value := range fields {
+		trans.setField(field, value)
+	}
+
+	return trans
+}
+
+// Difference between the expected and the actual result of a config
+// test
+type TestDifference struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (d TestDifference) String() string {
+	return fmt.Sprintf("%s: expected `%s', got `%s'", d.Field, d.Expected, d.Actual)
+}
+
+// Build the transaction of the config test
+func newTestTransaction(test cfg.ConfigTest, config cfg.Config) (Transaction, error) {
+	bank := &cfg.Bank{}
+	if test.Bank != "" {
+		var exists bool
+		bank, exists = config.Banks[test.Bank]
+		if !exists {
+			return Transaction{}, fmt.Errorf("bank `%s' not found in config", test.Bank)
+		}
+	}
+
+	fields := make(map[string]string)
+	for field, value := range test.Fields {
+		fields[field] = value
+	}
+	if test.PayeeRaw != "" {
+		fields["payeeRaw"] = test.PayeeRaw
+	}
+	if test.Amount != nil {
+		amount := strconv.FormatFloat(*test.Amount, 'f', -1, 64)
+		fields["amountAccount"] = amount
+		fields["amountReal"] = amount
+	}
+	if test.Currency != "" {
+		fields["currencyAccount"] = test.Currency
+	}
+	if test.Note != "" {
+		fields["noteForMe"] = test.Note
+	}
+	if test.Date != nil {
+		if bank.DatePatternFrom == "" {
+			withLayout := *bank
+			withLayout.DatePatternFrom = cfg.DateLayout
+			bank = &withLayout
+		}
+		fields["dateRaw"] = test.Date.Format(bank.DatePatternFrom)
+	}
+
+	return New(fields, config, bank), nil
+}
+
+// Account of the transaction, empty if the payee has none.  Errors
+// of the account template are returned instead of panicking.
+func (t Transaction) testAccountTo() (account string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if account, accountTemplate := t.accountOrTemplate(); account == "" && accountTemplate == "" {
+		return "", nil
+	}
+
+	return t.formatAccountTo(), nil
+}
+
+// Run the transaction of the config test through payee matching,
+// account resolution, ignoring and twin detection and return the
+// differences from the expectations
+func RunConfigTest(test cfg.ConfigTest, config cfg.Config) ([]TestDifference, error) {
+	trans, err := newTestTransaction(test, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var differences []TestDifference
+	check := func(field string, expected string, actual string) {
+		if expected != actual {
+			differences = append(differences, TestDifference{field, expected, actual})
+		}
+	}
+
+	if test.ExpectPayee != "" {
+		payee, _ := trans.GetPayee()
+		check("payee", test.ExpectPayee, payee.Name)
+	}
+
+	if test.ExpectAccount != "" {
+		account, err := trans.testAccountTo()
+		if err != nil {
+			return nil, fmt.Errorf("account template failed: %s", err)
+		}
+		check("account", test.ExpectAccount, account)
+	}
+
+	if test.ExpectIgnored != nil {
+		check("ignored", strconv.FormatBool(*test.ExpectIgnored), strconv.FormatBool(trans.IsIgnored()))
+	}
+
+	if test.ExpectTwin != "" {
+		twin := "none"
+		if tt := trans.IsTwinTransactionAnchor(); tt != nil {
+			twin = tt.Type
+		}
+		check("twin", test.ExpectTwin, twin)
+	}
+
+	return differences, nil
+}
@@ -1,10 +1,12 @@
 package transaction
 
 import (
+	"bank-to-ledger/bankaccount"
 	cfg "bank-to-ledger/config"
 	tmpl "bank-to-ledger/templating"
 
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -38,12 +40,24 @@ type Transaction struct {
 	NoteForMe       string
 	NoteForReceiver string
 
+	// Czech payment symbols as exported, e.g. constant symbol `0308',
+	// compared by config.NormalizeSymbol
+	VariableSymbol string
+	ConstantSymbol string
+	SpecificSymbol string
+
+	// Custom fields, see config.Bank.CustomColumns
+	Custom map[string]string
+
 	config cfg.Config
 	bank   *cfg.Bank
 
 	// cached payee object
 	payee   *cfg.Payee
 	pattern *cfg.PayeePattern
+
+	// meta added by AddMeta
+	extraMeta map[string]string
 }
 
 type CurrencyInfo struct {
@@ -56,18 +70,97 @@ func normalizeAmount(amount string) string {
 	return strings.ReplaceAll(amountNoComma, " ", "")
 }
 
-func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transaction {
-	ci := bank.ColumnIndices
+// Return the value of column at index or empty string if the column
+// is not present (index -1)
+func getColumn(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+
+	return record[index]
+}
+
+func parseAmount(amount string) float64 {
+	value, _ := strconv.ParseFloat(normalizeAmount(amount), 64)
+	return value
+}
+
+// Parse amount extracted from a text.  If it has no explicit sign, use
+// the sign of the current value, because the extracted amounts are
+// usually unsigned.
+func parseSignedAmount(amount string, current float64) float64 {
+	value := parseAmount(amount)
+	amount = strings.TrimSpace(amount)
 
-	amountAccountNormalized := normalizeAmount(record[ci.AmountAccount])
-	amountRealNormalized := normalizeAmount(record[ci.AmountReal])
+	if current < 0 && !strings.HasPrefix(amount, "-") && !strings.HasPrefix(amount, "+") {
+		return -math.Abs(value)
+	}
+
+	return value
+}
+
+// Convert the account number to the canonical form (see
+// bankaccount.Normalize) and warn about invalid checksums.  Numbers
+// in other formats, e.g. of foreign banks, are kept as they are.
+func normalizeAccountNumber(number string) string {
+	if strings.TrimSpace(number) == "" {
+		return ""
+	}
 
-	if amountRealNormalized == "" {
-		amountRealNormalized = amountAccountNormalized
+	normalized, err := bankaccount.Normalize(number)
+	if errors.Is(err, bankaccount.ErrUnknownFormat) {
+		return strings.TrimSpace(number)
 	}
+	if err != nil {
+		log.Printf("Account number `%s': %s", number, err)
+	}
+
+	return normalized
+}
+
+// Compute the signed amount in account currency and the real amount.
+// The account amount is either read from a signed column or computed
+// from separate debit and credit columns.  If a direction column is
+// present, it determines the sign of both amounts.
+func getAmounts(record []string, bank *cfg.Bank) (float64, float64) {
+	ci := bank.ColumnIndices
+
+	amountAccountNormalized := normalizeAmount(getColumn(record, ci.AmountAccount))
+	amountRealNormalized := normalizeAmount(getColumn(record, ci.AmountReal))
 
 	AmountAccount, _ := strconv.ParseFloat(amountAccountNormalized, 64)
-	AmountReal, _ := strconv.ParseFloat(amountRealNormalized, 64)
+
+	if ci.AmountAccount == -1 && (ci.AmountDebit != -1 || ci.AmountCredit != -1) {
+		debit := parseAmount(getColumn(record, ci.AmountDebit))
+		credit := parseAmount(getColumn(record, ci.AmountCredit))
+		AmountAccount = math.Abs(credit) - math.Abs(debit)
+	}
+
+	AmountReal := AmountAccount
+	if amountRealNormalized != "" {
+		AmountReal, _ = strconv.ParseFloat(amountRealNormalized, 64)
+	}
+
+	if ci.Direction != -1 {
+		switch bank.GetDirection(getColumn(record, ci.Direction)) {
+		case cfg.DirectionDebit:
+			AmountAccount = -math.Abs(AmountAccount)
+			AmountReal = -math.Abs(AmountReal)
+		case cfg.DirectionCredit:
+			AmountAccount = math.Abs(AmountAccount)
+			AmountReal = math.Abs(AmountReal)
+		default:
+			log.Printf("Unknown direction `%s' in bank %s", getColumn(record, ci.Direction), bank.Name)
+		}
+	}
+
+	return AmountAccount, AmountReal
+}
+
+func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transaction {
+	ci := bank.ColumnIndices
+
+	AmountAccount, AmountReal := getAmounts(record, bank)
 
 	var Fee float64
 	if ci.Fee == -1 || record[ci.Fee] == "" {
@@ -76,7 +169,7 @@ func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transacti
 		Fee, _ = strconv.ParseFloat(normalizeAmount(record[ci.Fee]), 64)
 	}
 
-	currencyRaw := record[ci.CurrencyRaw]
+	currencyRaw := getColumn(record, ci.CurrencyRaw)
 
 	currencyAccount := ""
 	if ci.CurrencyAccount != -1 {
@@ -101,10 +194,12 @@ func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transacti
 		receiverAccountNumber = record[ci.ReceiverAccountNumber]
 	}
 
-	if ci.ReceiverBankCode != -1 {
+	if ci.ReceiverBankCode != -1 && receiverAccountNumber != "" && record[ci.ReceiverBankCode] != "" {
 		receiverAccountNumber = receiverAccountNumber + "/" + record[ci.ReceiverBankCode]
 	}
 
+	receiverAccountNumber = normalizeAccountNumber(receiverAccountNumber)
+
 	commodity := ""
 	if ci.Commodity != -1 {
 		commodity = record[ci.Commodity]
@@ -120,12 +215,12 @@ func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transacti
 		commodityQuantity, _ = strconv.ParseFloat(normalizeAmount(record[ci.CommodityQuantity]), 64)
 	}
 
-	return Transaction{
-		DateRaw:         record[ci.DateRaw],
-		PaymentType:     record[ci.PaymentType],
+	trans := Transaction{
+		DateRaw:         getColumn(record, ci.DateRaw),
+		PaymentType:     getColumn(record, ci.PaymentType),
 		CurrencyRaw:     currencyRaw,
 		CurrencyAccount: currencyAccount,
-		PayeeRaw:        record[ci.PayeeRaw],
+		PayeeRaw:        getColumn(record, ci.PayeeRaw),
 
 		Commodity:         commodity,
 		CommodityPrice:    commodityPrice,
@@ -140,9 +235,69 @@ func FromCsvRecord(record []string, config cfg.Config, bank *cfg.Bank) Transacti
 
 		ReceiverAccountNumber: receiverAccountNumber,
 
+		VariableSymbol: cleanSymbol(getColumn(record, ci.VariableSymbol)),
+		ConstantSymbol: cleanSymbol(getColumn(record, ci.ConstantSymbol)),
+		SpecificSymbol: cleanSymbol(getColumn(record, ci.SpecificSymbol)),
+
+		Custom: make(map[string]string),
+
 		config: config,
 		bank:   bank,
 	}
+
+	for field, index := range bank.CustomColumnIndices {
+		trans.Custom[field] = getColumn(record, index)
+	}
+
+	for _, df := range bank.DerivedFields {
+		for field, value := range df.Extract(record) {
+			trans.setField(field, value)
+		}
+	}
+
+	return trans
+}
+
+// Set transaction field by its config name (see config.DerivedField).
+// Unknown names are set as custom fields.
+func (t *Transaction) setField(field string, value string) {
+	switch field {
+	case "dateRaw":
+		t.DateRaw = value
+	case "payeeRaw":
+		t.PayeeRaw = value
+	case "currencyRaw":
+		t.CurrencyRaw = value
+	case "currencyAccount":
+		t.CurrencyAccount = value
+	case "paymentType":
+		t.PaymentType = value
+	case "commodity":
+		t.Commodity = value
+	case "amountReal":
+		t.AmountReal = parseSignedAmount(value, t.AmountReal)
+	case "amountAccount":
+		t.AmountAccount = parseSignedAmount(value, t.AmountAccount)
+	case "fee":
+		t.Fee = parseAmount(value)
+	case "receiverAccountNumber":
+		t.ReceiverAccountNumber = normalizeAccountNumber(value)
+	case "noteForMe":
+		t.NoteForMe = value
+	case "noteForReceiver":
+		t.NoteForReceiver = value
+	case "variableSymbol":
+		t.VariableSymbol = cleanSymbol(value)
+	case "constantSymbol":
+		t.ConstantSymbol = cleanSymbol(value)
+	case "specificSymbol":
+		t.SpecificSymbol = cleanSymbol(value)
+	default:
+		if t.Custom == nil {
+			t.Custom = make(map[string]string)
+		}
+		t.Custom[strings.TrimPrefix(field, cfg.CustomFieldPrefix)] = value
+	}
 }
 
 func (t Transaction) FormatDate() string {
@@ -150,6 +305,37 @@ func (t Transaction) FormatDate() string {
 	return tt.Format("2006/01/02")
 }
 
+// Date of the transaction, false if it cannot be parsed
+func (t Transaction) GetDate() (time.Time, bool) {
+	date, err := time.Parse(t.bank.DatePatternFrom, strings.TrimSpace(t.DateRaw))
+	return date, err == nil
+}
+
+// Dates already warned about as unparseable, by bank name and raw
+// date, so each is reported once
+var warnedDates = make(map[[2]string]bool)
+
+// Report whether the transaction's date is in the range.  A date which
+// cannot be parsed is in no bounded range, so a date-bounded payee,
+// pattern or account rule is not applied to the wrong period.
+func (t Transaction) inDateRange(r cfg.DateRange) bool {
+	if !r.IsBounded() {
+		return true
+	}
+
+	date, known := t.GetDate()
+	if !known {
+		key := [2]string{t.bank.Name, t.DateRaw}
+		if !warnedDates[key] {
+			warnedDates[key] = true
+			log.Printf("Date `%s' of bank %s does not match datePatternFrom `%s', date-bounded payees, patterns and account rules are skipped", t.DateRaw, t.bank.Name, t.bank.DatePatternFrom)
+		}
+		return false
+	}
+
+	return r.Contains(date)
+}
+
 func (t Transaction) GetCurrency() CurrencyInfo {
 	return t.GetCurrencyBySymbol(t.CurrencyRaw)
 }
@@ -246,11 +432,37 @@ func (t Transaction) FormatAmountRealInverted(buffer *TransactionBuffer) string
 	return t.formatAmountReal(amount)
 }
 
+// Trim the payment symbol.  Symbols consisting only of zeros are
+// treated as empty, many banks export them instead of an empty value.
+func cleanSymbol(symbol string) string {
+	symbol = strings.TrimSpace(symbol)
+	if cfg.NormalizeSymbol(symbol) == "" {
+		return ""
+	}
+
+	return symbol
+}
+
+func (t Transaction) matchSymbol(patterns cfg.PayeePatterns, symbol string) *cfg.PayeePattern {
+	symbol = cfg.NormalizeSymbol(symbol)
+	if symbol == "" {
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		if cfg.NormalizeSymbol(pattern.Value) == symbol && t.inDateRange(pattern.DateRange) {
+			return &pattern
+		}
+	}
+
+	return nil
+}
+
 func (t Transaction) matchPayee(p *cfg.Payee) *cfg.PayeePattern {
 	if p.PayeeRaw != nil {
 		for _, pattern := range p.PayeeRaw {
 			match, _ := regexp.MatchString("(?i)"+pattern.Value, t.PayeeRaw)
-			if match {
+			if match && t.inDateRange(pattern.DateRange) {
 				pattern.Type = "PayeeRaw"
 				return &pattern
 			}
@@ -259,16 +471,31 @@ func (t Transaction) matchPayee(p *cfg.Payee) *cfg.PayeePattern {
 
 	if p.ReceiverAccountNumber != nil {
 		for _, pattern := range p.ReceiverAccountNumber {
-			if pattern.Value == t.ReceiverAccountNumber {
+			if bankaccount.Equal(pattern.Value, t.ReceiverAccountNumber) && t.inDateRange(pattern.DateRange) {
 				pattern.Type = "ReceiverAccountNumber"
 				return &pattern
 			}
 		}
 	}
 
+	if pattern := t.matchSymbol(p.VariableSymbol, t.VariableSymbol); pattern != nil {
+		pattern.Type = "VariableSymbol"
+		return pattern
+	}
+
+	if pattern := t.matchSymbol(p.ConstantSymbol, t.ConstantSymbol); pattern != nil {
+		pattern.Type = "ConstantSymbol"
+		return pattern
+	}
+
+	if pattern := t.matchSymbol(p.SpecificSymbol, t.SpecificSymbol); pattern != nil {
+		pattern.Type = "SpecificSymbol"
+		return pattern
+	}
+
 	if p.PaymentType != nil {
 		for _, pattern := range p.PaymentType {
-			if pattern.Value == t.PaymentType {
+			if pattern.Value == t.PaymentType && t.inDateRange(pattern.DateRange) {
 				pattern.Type = "PaymentType"
 				return &pattern
 			}
@@ -278,13 +505,28 @@ func (t Transaction) matchPayee(p *cfg.Payee) *cfg.PayeePattern {
 	if p.NoteForMe != nil {
 		for _, pattern := range p.NoteForMe {
 			match, _ := regexp.MatchString("(?i)"+pattern.Value, t.NoteForMe)
-			if match {
+			if match && t.inDateRange(pattern.DateRange) {
 				pattern.Type = "NoteForMe"
 				return &pattern
 			}
 		}
 	}
 
+	for field, patterns := range p.Custom {
+		value, exists := t.Custom[field]
+		if !exists {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			match, _ := regexp.MatchString("(?i)"+pattern.Value, value)
+			if match && t.inDateRange(pattern.DateRange) {
+				pattern.Type = "Custom." + field
+				return &pattern
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -294,6 +536,9 @@ func (t *Transaction) GetPayee() (*cfg.Payee, bool) {
 	}
 
 	for _, pv := range t.config.Payees {
+		if !t.inDateRange(pv.DateRange) {
+			continue
+		}
 		if pattern := t.matchPayee(pv); pattern != nil {
 			t.payee = pv
 			t.pattern = pattern
@@ -301,42 +546,124 @@ func (t *Transaction) GetPayee() (*cfg.Payee, bool) {
 		}
 	}
 
+	if bank := t.CounterpartyBank(); bank.Name != "" {
+		return cfg.GetUnknownPayeeAtBank(t.PayeeRaw, bank.Name), false
+	}
+
 	return cfg.GetUnknownPayee(t.PayeeRaw), false
 }
 
-func (t Transaction) GetNote() string {
-	payee, _ := t.GetPayee()
-	note := []string{"(^.^)"}
-	payeeName := payee.Name
+// The pattern the payee was matched by, nil for unknown payees
+func (t *Transaction) GetPattern() *cfg.PayeePattern {
+	t.GetPayee()
+	return t.pattern
+}
 
-	if payeeName == "RegioJet" {
-		note = append(note, "check if credit")
+// The bank the transaction was read from
+func (t Transaction) GetBank() *cfg.Bank {
+	return t.bank
+}
+
+// Bank of the counterparty determined from the receiver account
+// number.  Returns empty BankInfo if the bank is not known.
+func (t Transaction) CounterpartyBank() bankaccount.BankInfo {
+	if t.ReceiverAccountNumber == "" {
+		return bankaccount.BankInfo{}
 	}
 
-	if slices.Contains(t.config.PayeeIsTravel, payeeName) {
-		note = append(note, "add to/from/location")
+	bank, _ := bankaccount.LookupAccount(t.ReceiverAccountNumber)
+	return bank
+}
+
+// Report whether the note rule applies to the transaction with the
+// payee
+func (t Transaction) matchesNote(rule cfg.NoteRule, payee *cfg.Payee) bool {
+	if len(rule.Payee) > 0 && !slices.Contains(rule.Payee, payee.Name) {
+		return false
 	}
 
-	if slices.Contains([]string{"Alza", "Tesco", "Tiger"}, payeeName) {
-		note = append(note, "what did you get from there?")
+	if len(rule.Group) > 0 && !slices.ContainsFunc(rule.Group, payee.InGroup) {
+		return false
 	}
 
-	if payeeName == "Unknown hotel" {
-		note = append(note, "add which hotel it is")
+	if rule.Travel && !payee.InGroup(cfg.TravelGroup) {
+		return false
 	}
 
-	if payeeName == "Small chinese shop" {
-		note = append(
-			note,
-			"this is prob the small shop next to your place but check",
-		)
+	return t.matchesAmount(rule.Amount)
+}
+
+// Report whether the direction and amount of the transaction satisfy
+// the condition
+func (t Transaction) matchesAmount(c cfg.AmountCondition) bool {
+	amount := math.Abs(t.AmountAccount)
+	if c.MinAmount != nil && amount < *c.MinAmount {
+		return false
+	}
+	if c.MaxAmount != nil && amount > *c.MaxAmount {
+		return false
 	}
 
-	if len(note) > 1 {
-		return " " + strings.Join(note, ", ")
-	} else {
+	switch c.Sign {
+	case cfg.SignIncoming:
+		return t.AmountAccount > 0
+	case cfg.SignOutgoing:
+		return t.AmountAccount < 0
+	}
+
+	return true
+}
+
+// Note rules from the config applying to the transaction
+func (t Transaction) GetNotes() []cfg.NoteRule {
+	if len(t.config.Notes) == 0 {
+		return nil
+	}
+
+	payee, _ := t.GetPayee()
+
+	var notes []cfg.NoteRule
+	for _, rule := range t.config.Notes {
+		if t.matchesNote(rule, payee) {
+			notes = append(notes, rule)
+		}
+	}
+
+	return notes
+}
+
+// Notes appended to the transaction header as a comment, preceded by
+// the marker, e.g. `  ; (^.^) refund?`
+func (t Transaction) GetNote() string {
+	var note []string
+	for _, rule := range t.GetNotes() {
+		if rule.As != cfg.NoteAsMeta && rule.Text != "" {
+			note = append(note, rule.Text)
+		}
+	}
+
+	if len(note) == 0 {
 		return ""
 	}
+
+	text := strings.Join(note, ", ")
+	if marker := t.config.GetNoteMarker(); marker != "" {
+		text = marker + " " + text
+	}
+
+	return "  ; " + text
+}
+
+// Status of the transaction in the output, `!` (pending) if a note
+// requires it, otherwise `*` (cleared)
+func (t Transaction) GetStatus() string {
+	for _, rule := range t.GetNotes() {
+		if rule.As == cfg.NoteAsPending {
+			return "!"
+		}
+	}
+
+	return "*"
 }
 
 func (t Transaction) resolveTemplate(template string) string {
@@ -366,7 +693,7 @@ func (t Transaction) Match(matchers []cfg.Matcher) bool {
 			isMatch = isMatch && t.PaymentType == matcher.PaymentType
 		}
 		if matcher.ReceiverAccountNumber != "" {
-			isMatch = isMatch && t.ReceiverAccountNumber == matcher.ReceiverAccountNumber
+			isMatch = isMatch && bankaccount.Equal(t.ReceiverAccountNumber, matcher.ReceiverAccountNumber)
 		}
 		if matcher.PayeeRaw != "" {
 			isMatch = isMatch && t.PayeeRaw == matcher.PayeeRaw
@@ -379,12 +706,28 @@ func (t Transaction) Match(matchers []cfg.Matcher) bool {
 				isMatch = false
 			}
 		}
+		if matcher.Group != "" {
+			payee, exists := t.GetPayee()
+			isMatch = isMatch && exists && payee.InGroup(matcher.Group)
+		}
 		if matcher.NoteForMe != "" {
 			isMatch = isMatch && t.NoteForMe == matcher.NoteForMe
 		}
 		if matcher.NoteForReceiver != "" {
 			isMatch = isMatch && t.NoteForReceiver == matcher.NoteForReceiver
 		}
+		if matcher.VariableSymbol != "" {
+			isMatch = isMatch && cfg.NormalizeSymbol(t.VariableSymbol) == cfg.NormalizeSymbol(matcher.VariableSymbol)
+		}
+		if matcher.ConstantSymbol != "" {
+			isMatch = isMatch && cfg.NormalizeSymbol(t.ConstantSymbol) == cfg.NormalizeSymbol(matcher.ConstantSymbol)
+		}
+		if matcher.SpecificSymbol != "" {
+			isMatch = isMatch && cfg.NormalizeSymbol(t.SpecificSymbol) == cfg.NormalizeSymbol(matcher.SpecificSymbol)
+		}
+		for field, value := range matcher.Custom {
+			isMatch = isMatch && t.Custom[field] == value
+		}
 
 		if isMatch {
 			return true
@@ -522,21 +865,74 @@ func (t Transaction) GetMeta(payee string) map[string]string {
 		t.getMetaFromStruct(meta, metaOut)
 	}
 
+	if t.VariableSymbol != "" {
+		metaOut["VS"] = t.VariableSymbol
+	}
+
+	if t.ConstantSymbol != "" {
+		metaOut["KS"] = t.ConstantSymbol
+	}
+
+	if t.SpecificSymbol != "" {
+		metaOut["SS"] = t.SpecificSymbol
+	}
+
+	if t.payee == nil {
+		if bank := t.CounterpartyBank(); bank.Name != "" {
+			metaOut["CounterpartyBank"] = bank.Name
+			if bank.BIC != "" {
+				metaOut["CounterpartyBIC"] = bank.BIC
+			}
+		}
+	}
+
+	if t.bank.Meta != nil {
+		for k, v := range *t.bank.Meta {
+			if value := t.FormatTextTemplate(v); value != "" {
+				metaOut[k] = value
+			}
+		}
+	}
+
 	if t.payee != nil && t.payee.Meta != nil {
 		for k, v := range *t.payee.Meta {
 			metaOut[k] = t.FormatTextTemplate(v)
 		}
 	}
 
+	for _, rule := range t.GetNotes() {
+		if rule.As != cfg.NoteAsMeta {
+			continue
+		}
+		if value, exists := metaOut[rule.GetKey()]; exists {
+			metaOut[rule.GetKey()] = value + ", " + rule.Text
+		} else {
+			metaOut[rule.GetKey()] = rule.Text
+		}
+	}
+
 	if t.pattern != nil && t.pattern.Meta != nil {
 		for k, v := range *t.pattern.Meta {
 			metaOut[k] = t.FormatTextTemplate(v)
 		}
 	}
 
+	for k, v := range t.extraMeta {
+		metaOut[k] = v
+	}
+
 	return metaOut
 }
 
+// Add meta which is not derived from the config, e.g. suggestions
+func (t *Transaction) AddMeta(key string, value string) {
+	if t.extraMeta == nil {
+		t.extraMeta = make(map[string]string)
+	}
+
+	t.extraMeta[key] = value
+}
+
 func (t Transaction) getTemplateContext() tmpl.TextTemplateParams {
 	p, _ := t.GetPayee()
 
@@ -548,6 +944,7 @@ func (t Transaction) getTemplateContext() tmpl.TextTemplateParams {
 			AccountName:    t.bank.AccountName,
 			FeeAccountName: t.bank.FeeAccountName,
 			Templates:      t.bank.Templates,
+			Preamble:       t.bank.Preamble,
 		},
 		Transaction: tmpl.TextTemplateTransaction{
 			DateRaw:         t.DateRaw,
@@ -568,10 +965,19 @@ func (t Transaction) getTemplateContext() tmpl.TextTemplateParams {
 
 			NoteForMe:       t.NoteForMe,
 			NoteForReceiver: t.NoteForReceiver,
+
+			VariableSymbol: t.VariableSymbol,
+			ConstantSymbol: t.ConstantSymbol,
+			SpecificSymbol: t.SpecificSymbol,
+
+			CounterpartyBank: tmpl.TextTemplateBankInfo(t.CounterpartyBank()),
+
+			Custom: t.Custom,
 		},
 		Payee: tmpl.TextTemplatePayee{
 			Name:    p.Name,
 			Account: p.Account,
+			Groups:  p.Groups,
 		},
 	}
 }
@@ -586,22 +992,77 @@ func (t Transaction) formatPayee() string {
 	return tmpl.FormatTextTemplate(p.Template, t.getTemplateContext())
 }
 
+// The account the transaction is categorized to
+func (t Transaction) GetAccountTo() string {
+	return t.formatAccountTo()
+}
+
+// The account rule of the payee matching the transaction, nil if
+// there is none
+func (t Transaction) GetAccountRule() *cfg.AccountRule {
+	p, _ := t.GetPayee()
+
+	for i := range p.Accounts {
+		if t.inDateRange(p.Accounts[i].DateRange) && t.matchesAmount(p.Accounts[i].Amount) {
+			return &p.Accounts[i]
+		}
+	}
+
+	return nil
+}
+
+// The config key of the payee's account used for the direction of the
+// transaction, `incomingAccount` or `outgoingAccount`, empty if the
+// payee has none
+func (t Transaction) GetDirectionAccountKey() string {
+	p, _ := t.GetPayee()
+
+	if t.AmountAccount > 0 && p.IncomingAccount != "" {
+		return "incomingAccount"
+	}
+	if t.AmountAccount < 0 && p.OutgoingAccount != "" {
+		return "outgoingAccount"
+	}
+
+	return ""
+}
+
+// The account and account template of the payee used for the
+// transaction
+func (t Transaction) accountOrTemplate() (string, string) {
+	if rule := t.GetAccountRule(); rule != nil {
+		return rule.Account, rule.AccountTemplate
+	}
+
+	p, _ := t.GetPayee()
+	switch t.GetDirectionAccountKey() {
+	case "incomingAccount":
+		return p.IncomingAccount, ""
+	case "outgoingAccount":
+		return p.OutgoingAccount, ""
+	}
+
+	return p.Account, p.AccountTemplate
+}
+
 func (t Transaction) formatAccountTo() string {
 	p, _ := t.GetPayee()
 
-	if p.AccountTemplate == "" {
-		if p.Account == "" {
+	account, accountTemplate := t.accountOrTemplate()
+	if accountTemplate == "" {
+		if account == "" {
 			panic(fmt.Sprintf("No account assigned to payee %s", p.Name))
 		}
-		return p.Account
+		return account
 	}
 
-	return tmpl.FormatTextTemplate(p.AccountTemplate, t.getTemplateContext())
+	return tmpl.FormatTextTemplate(accountTemplate, t.getTemplateContext())
 }
 
 type TemplateContext struct {
 	Transaction             Transaction
 	Date                    string
+	Status                  string
 	Payee                   string
 	Note                    string
 	Meta                    string
@@ -623,12 +1084,15 @@ func (t Transaction) FormatTrans(buffer TransactionBuffer) string {
 	for k, v := range meta {
 		metaLines = append(metaLines, fmt.Sprintf("    ; %s: %s\n", k, v))
 	}
+	if len(payee.Tags) > 0 {
+		metaLines = append(metaLines, fmt.Sprintf("    ; :%s:\n", strings.Join(payee.Tags, ":")))
+	}
 
 	funcMap := template.FuncMap{
 		"replace": strings.ReplaceAll,
 	}
 
-	tmpl, err := template.New("transaction").Funcs(funcMap).Parse(`{{ .Date }} * {{ .Payee }}{{ .Note }}
+	tmpl, err := template.New("transaction").Funcs(funcMap).Parse(`{{ .Date }} {{ .Status }} {{ .Payee }}{{ .Note }}
 {{ .Meta }}    {{ .AccountTo }}
 {{- if .Transaction.CommodityQuantity }}  {{ .Transaction.CommodityQuantity }} {{ replace .Transaction.Commodity " " "_" }} @ {{ .CommodityPriceFormatted }}
 {{- else }}      {{ .AccountToAmount }}
@@ -655,6 +1119,7 @@ func (t Transaction) FormatTrans(buffer TransactionBuffer) string {
 	context := TemplateContext{
 		Transaction:             t,
 		Date:                    t.FormatDate(),
+		Status:                  t.GetStatus(),
 		Payee:                   t.formatPayee(),
 		Note:                    t.GetNote(),
 		Meta:                    strings.Join(metaLines, ""),
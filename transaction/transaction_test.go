@@ -3,7 +3,12 @@ package transaction
 import (
 	cfg "bank-to-ledger/config"
 
+	"bytes"
+	"log"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -306,3 +311,659 @@ func TestTransactionAccountToAmountFormatting_with_conversion(t *testing.T) {
 	assert.NotNil(t, meta)
 	assert.Equal(t, "10.00 PLN @@ 250.00 Kc", transaction.FormatAmountRealInverted(nil))
 }
+
+func getAmountsBank() *cfg.Bank {
+	return &cfg.Bank{
+		Name: "Foo",
+		ColumnIndices: cfg.ColumnIndices{
+			AmountAccount: -1,
+			AmountReal:    -1,
+			AmountDebit:   0,
+			AmountCredit:  1,
+			Direction:     -1,
+		},
+	}
+}
+
+func TestGetAmounts_debit_column(t *testing.T) {
+	amountAccount, amountReal := getAmounts([]string{"1 200,50", ""}, getAmountsBank())
+
+	assert.Equal(t, -1200.5, amountAccount)
+	assert.Equal(t, -1200.5, amountReal)
+}
+
+func TestGetAmounts_credit_column(t *testing.T) {
+	amountAccount, amountReal := getAmounts([]string{"", "300"}, getAmountsBank())
+
+	assert.Equal(t, 300.0, amountAccount)
+	assert.Equal(t, 300.0, amountReal)
+}
+
+func TestGetAmounts_direction_column(t *testing.T) {
+	bank := &cfg.Bank{
+		Name: "Foo",
+		ColumnIndices: cfg.ColumnIndices{
+			AmountAccount: 0,
+			AmountReal:    -1,
+			AmountDebit:   -1,
+			AmountCredit:  -1,
+			Direction:     1,
+		},
+		DirectionValues: map[string]string{
+			"Odchozí": cfg.DirectionDebit,
+		},
+	}
+
+	amountAccount, _ := getAmounts([]string{"250", "Odchozí"}, bank)
+	assert.Equal(t, -250.0, amountAccount)
+
+	amountAccount, _ = getAmounts([]string{"250", "C"}, bank)
+	assert.Equal(t, 250.0, amountAccount)
+}
+
+func TestFromCsvRecord_with_derived_fields(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.PayeeRaw = 1
+	ci.CurrencyRaw = 2
+
+	bank := &cfg.Bank{
+		Name:          "Foo",
+		ColumnIndices: ci,
+		DerivedFields: []cfg.DerivedField{
+			{
+				ColumnIndices: []int{1},
+				Pattern:       `^Nákup: (?P<merchant>[^,]+), .*částka (?P<amount>[0-9.]+) (?P<currency>[A-Z]{3})`,
+				Fields: map[string]string{
+					"payeeRaw":    "${merchant}",
+					"amountReal":  "${amount}",
+					"currencyRaw": "${currency}",
+				},
+			},
+		},
+	}
+
+	err := bank.ResolveDerivedFields(nil)
+	assert.Nil(t, err)
+
+	transaction := FromCsvRecord([]string{
+		"-3 100,00",
+		"Nákup: ALZA.CZ, Praha, CZ, dne 1.2.2024, částka 123.00 EUR",
+		"CZK",
+	}, cfg.Config{}, bank)
+
+	assert.Equal(t, "ALZA.CZ", transaction.PayeeRaw)
+	assert.Equal(t, "EUR", transaction.CurrencyRaw)
+	assert.Equal(t, "CZK", transaction.CurrencyAccount)
+	assert.Equal(t, -123.0, transaction.AmountReal)
+	assert.Equal(t, -3100.0, transaction.AmountAccount)
+}
+
+func TestResolveDerivedFields_misspelled_target(t *testing.T) {
+	bank := &cfg.Bank{
+		DerivedFields: []cfg.DerivedField{
+			{ColumnIndices: []int{0}, Pattern: `VS(\d+)`, Fields: map[string]string{"varaibleSymbol": "$1"}},
+		},
+	}
+
+	err := bank.ResolveDerivedFields(nil)
+	assert.EqualError(t, err, "derivedFields[0] sets unknown field varaibleSymbol, did you mean variableSymbol? Use custom.varaibleSymbol for a custom field")
+}
+
+func TestFromCsvRecord_with_derived_custom_field(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.PayeeRaw = 1
+
+	bank := &cfg.Bank{
+		Name:          "Foo",
+		ColumnIndices: ci,
+		DerivedFields: []cfg.DerivedField{
+			{ColumnIndices: []int{1}, Pattern: `MCC (\d+)`, Fields: map[string]string{"mcc": "$1", "custom.paymentTyp": "card"}},
+		},
+	}
+
+	err := bank.ResolveDerivedFields(nil)
+	assert.Nil(t, err)
+
+	transaction := FromCsvRecord([]string{"-250", "U Fleku MCC 5812"}, cfg.Config{}, bank)
+
+	assert.Equal(t, map[string]string{"mcc": "5812", "paymentTyp": "card"}, transaction.Custom)
+}
+
+func TestFromCsvRecord_with_custom_fields(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.PayeeRaw = 1
+
+	bank := &cfg.Bank{
+		Name:                "Foo",
+		ColumnIndices:       ci,
+		CustomColumnIndices: map[string]int{"mcc": 2},
+	}
+
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Restaurant": {
+				Name:    "Restaurant",
+				Account: "Expenses:Restaurant",
+				Custom: map[string]cfg.PayeePatterns{
+					"mcc": {{Value: "^581[24]$"}},
+				},
+			},
+		},
+	}
+
+	transaction := FromCsvRecord([]string{"-250", "U Fleku", "5812"}, config, bank)
+
+	assert.Equal(t, "5812", transaction.Custom["mcc"])
+
+	payee, exists := transaction.GetPayee()
+	assert.True(t, exists)
+	assert.Equal(t, "Restaurant", payee.Name)
+
+	assert.True(t, transaction.Match([]cfg.Matcher{{Custom: map[string]string{"mcc": "5812"}}}))
+	assert.False(t, transaction.Match([]cfg.Matcher{{Custom: map[string]string{"mcc": "5411"}}}))
+}
+
+func TestFromCsvRecord_with_payment_symbols(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.PayeeRaw = 1
+	ci.VariableSymbol = 2
+	ci.ConstantSymbol = 3
+
+	bank := &cfg.Bank{
+		Name:          "Foo",
+		ColumnIndices: ci,
+	}
+
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Rent": {
+				Name:           "Rent",
+				Account:        "Expenses:Housing:Rent",
+				VariableSymbol: cfg.PayeePatterns{{Value: "1234567890"}},
+			},
+		},
+	}
+
+	transaction := FromCsvRecord([]string{"-15000", "Landlord", "001234567890", "0000"}, config, bank)
+
+	// leading zeros are kept for the output, ignored when matching
+	assert.Equal(t, "001234567890", transaction.VariableSymbol)
+	assert.Equal(t, "", transaction.ConstantSymbol)
+
+	payee, exists := transaction.GetPayee()
+	assert.True(t, exists)
+	assert.Equal(t, "Rent", payee.Name)
+	assert.True(t, transaction.Match([]cfg.Matcher{{VariableSymbol: "1234567890"}}))
+
+	meta := transaction.GetMeta(payee.Name)
+	assert.Equal(t, "001234567890", meta["VS"])
+	assert.NotContains(t, meta, "KS")
+
+	transaction = FromCsvRecord([]string{"-15000", "Landlord", "", " 0308 "}, config, bank)
+	assert.Equal(t, "0308", transaction.GetMeta("Landlord")["KS"])
+	assert.True(t, transaction.Match([]cfg.Matcher{{ConstantSymbol: "308"}}))
+}
+
+func TestGetPayee_receiverAccountNumber_in_iban_format(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.ReceiverAccountNumber = 1
+	ci.ReceiverBankCode = 2
+
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Savings": {
+				Name:                  "Savings",
+				Account:               "Assets:Savings",
+				ReceiverAccountNumber: cfg.PayeePatterns{{Value: "CZ65 0800 0000 1920 0014 5399"}},
+			},
+		},
+	}
+
+	transaction := FromCsvRecord([]string{"-1000", "000019-2000145399", "0800"}, config, &cfg.Bank{ColumnIndices: ci})
+
+	assert.Equal(t, "19-2000145399/0800", transaction.ReceiverAccountNumber)
+
+	payee, exists := transaction.GetPayee()
+	assert.True(t, exists)
+	assert.Equal(t, "Savings", payee.Name)
+}
+
+func TestNormalizeAccountNumber_warns_only_about_checksums(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	assert.Equal(t, "GB-12 3456 789", normalizeAccountNumber(" GB-12 3456 789 "))
+	assert.Equal(t, "", output.String())
+
+	normalizeAccountNumber("2000145398/0800")
+	assert.True(t, strings.Contains(output.String(), "invalid checksum"), output.String())
+}
+
+func TestGetPayee_unknown_payee_with_counterparty_bank(t *testing.T) {
+	ci := cfg.NewColumnIndices()
+	ci.AmountAccount = 0
+	ci.PayeeRaw = 1
+	ci.ReceiverAccountNumber = 2
+
+	transaction := FromCsvRecord([]string{"-1000", "Plumber", "2000145399/2010"}, cfg.Config{}, &cfg.Bank{ColumnIndices: ci})
+
+	payee, exists := transaction.GetPayee()
+	assert.False(t, exists)
+	assert.Equal(t, "Unknown payee at Fio banka, a.s. ;Plumber", payee.Name)
+
+	meta := transaction.GetMeta(payee.Name)
+	assert.Equal(t, "Fio banka, a.s.", meta["CounterpartyBank"])
+	assert.Equal(t, "FIOBCZPP", meta["CounterpartyBIC"])
+}
+
+func TestGetNote_comment(t *testing.T) {
+	hundred := 100.0
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Tesco": {
+				Name:     "Tesco",
+				Account:  "Expenses:Groceries",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^tesco"}},
+			},
+		},
+		Notes: []cfg.NoteRule{
+			{Payee: cfg.Names{"Tesco", "Alza"}, Text: "what did you get from there?"},
+			{Amount: cfg.AmountCondition{MinAmount: &hundred, Sign: cfg.SignOutgoing}, Text: "big payment", As: cfg.NoteAsMeta, Key: "Review"},
+		},
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "Tesco", "amountAccount": "-50"}, config, &cfg.Bank{})
+
+	assert.Equal(t, "  ; (^.^) what did you get from there?", transaction.GetNote())
+	assert.Equal(t, "*", transaction.GetStatus())
+	_, exists := transaction.GetMeta("Tesco")["Review"]
+	assert.False(t, exists)
+}
+
+func TestGetNote_travel_and_pending(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"RegioJet": {
+				Name:     "RegioJet",
+				Account:  "Expenses:Travel",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^regiojet"}},
+				Groups:   cfg.Names{cfg.TravelGroup},
+			},
+		},
+		Notes: []cfg.NoteRule{
+			{Travel: true, Text: "add to/from/location"},
+			{Payee: cfg.Names{"RegioJet"}, Amount: cfg.AmountCondition{Sign: cfg.SignIncoming}, Text: "check if credit", As: cfg.NoteAsPending},
+		},
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "RegioJet", "amountAccount": "300"}, config, &cfg.Bank{})
+
+	assert.Equal(t, "  ; (^.^) add to/from/location, check if credit", transaction.GetNote())
+	assert.Equal(t, "!", transaction.GetStatus())
+
+	outgoing := New(map[string]string{"payeeRaw": "RegioJet", "amountAccount": "-30"}, config, &cfg.Bank{})
+	assert.Equal(t, "  ; (^.^) add to/from/location", outgoing.GetNote())
+	assert.Equal(t, "*", outgoing.GetStatus())
+}
+
+func TestGetNote_meta_and_marker(t *testing.T) {
+	hundred := 100.0
+	marker := ""
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Tesco": {
+				Name:     "Tesco",
+				Account:  "Expenses:Groceries",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^tesco"}},
+			},
+		},
+		Notes: []cfg.NoteRule{
+			{Payee: cfg.Names{"Tesco"}, Text: "what did you get from there?"},
+			{Amount: cfg.AmountCondition{MinAmount: &hundred, Sign: cfg.SignOutgoing}, Text: "big payment", As: cfg.NoteAsMeta, Key: "Review"},
+		},
+		NoteMarker: &marker,
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "Tesco", "amountAccount": "-150"}, config, &cfg.Bank{DatePatternFrom: "02.01.2006"})
+
+	assert.Equal(t, "  ; what did you get from there?", transaction.GetNote())
+	assert.Equal(t, "big payment", transaction.GetMeta("Tesco")["Review"])
+	assert.True(t, strings.Contains(transaction.FormatTrans(TransactionBuffer{}), "; Review: big payment"))
+}
+
+func TestGetNote_no_notes(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{},
+		Notes: []cfg.NoteRule{
+			{Payee: cfg.Names{"Tesco"}, Text: "what did you get from there?"},
+		},
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "Billa", "amountAccount": "-10"}, config, &cfg.Bank{})
+
+	assert.Equal(t, "", transaction.GetNote())
+}
+
+func TestGroups_template_note_and_tags(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Netflix": {
+				Name:            "Netflix",
+				AccountTemplate: "Expenses:{{ index .Payee.Groups 0 }}:{{ .Payee.Name }}",
+				PayeeRaw:        cfg.PayeePatterns{{Value: "^netflix"}},
+				Groups:          cfg.Names{"Subscriptions", "kids"},
+				Tags:            []string{"recurring", "kids"},
+			},
+		},
+		Notes: []cfg.NoteRule{
+			{Group: cfg.Names{"kids"}, Text: "ask the kids"},
+		},
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "NETFLIX.COM", "amountAccount": "-10"}, config, &cfg.Bank{})
+
+	assert.Equal(t, "Expenses:Subscriptions:Netflix", transaction.GetAccountTo())
+	assert.Equal(t, "  ; (^.^) ask the kids", transaction.GetNote())
+	assert.True(t, strings.Contains(transaction.FormatTrans(TransactionBuffer{}), "    ; :recurring:kids:\n"))
+}
+
+func TestMatch_group(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Netflix": {
+				Name:     "Netflix",
+				Account:  "Expenses:Subscriptions",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^netflix"}},
+				Groups:   cfg.Names{"kids"},
+			},
+		},
+	}
+
+	transaction := New(map[string]string{"payeeRaw": "NETFLIX.COM"}, config, &cfg.Bank{})
+
+	assert.True(t, transaction.Match([]cfg.Matcher{{Group: "kids"}}))
+	assert.False(t, transaction.Match([]cfg.Matcher{{Group: "travel"}}))
+
+	unknown := New(map[string]string{"payeeRaw": "Billa"}, config, &cfg.Bank{})
+	assert.False(t, unknown.Match([]cfg.Matcher{{Group: "kids"}}))
+}
+
+func TestAccountRules_date(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Electricity": {
+				Name:     "Electricity",
+				Account:  "Expenses:Utilities",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^pre"}},
+				Accounts: []cfg.AccountRule{
+					{
+						Account:   "Expenses:Flat1:Utilities",
+						DateRange: cfg.DateRange{ValidTo: &cfg.Date{Time: time.Date(2023, time.April, 30, 0, 0, 0, 0, time.UTC)}},
+					},
+					{
+						AccountTemplate: "Expenses:Flat2:{{ .Payee.Name }}",
+						DateRange: cfg.DateRange{
+							ValidFrom: &cfg.Date{Time: time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC)},
+							ValidTo:   &cfg.Date{Time: time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)},
+						},
+					},
+				},
+			},
+		},
+	}
+	bank := &cfg.Bank{DatePatternFrom: "02.01.2006"}
+
+	for date, expected := range map[string]string{
+		"30.04.2023": "Expenses:Flat1:Utilities",
+		"01.05.2023": "Expenses:Flat2:Electricity",
+		// the date is not known, no rule applies
+		"": "Expenses:Utilities",
+	} {
+		transaction := New(map[string]string{"payeeRaw": "PRE a.s.", "dateRaw": date}, config, bank)
+		assert.Equal(t, expected, transaction.GetAccountTo(), date)
+	}
+
+	// no rule matches, the payee's account is used
+	later := New(map[string]string{"payeeRaw": "PRE a.s.", "dateRaw": "01.01.2024"}, config, bank)
+	assert.Nil(t, later.GetAccountRule())
+	assert.Equal(t, "Expenses:Utilities", later.GetAccountTo())
+}
+
+func TestInDateRange_unparseable_date(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	bank := &cfg.Bank{Name: "dated", DatePatternFrom: "02.01.2006"}
+	transaction := New(map[string]string{"dateRaw": "2023-05-01"}, cfg.Config{}, bank)
+	bounded := cfg.DateRange{ValidFrom: &cfg.Date{Time: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)}}
+
+	assert.True(t, transaction.inDateRange(cfg.DateRange{}))
+	assert.Equal(t, "", output.String())
+
+	assert.False(t, transaction.inDateRange(bounded))
+	assert.False(t, transaction.inDateRange(bounded))
+	assert.Equal(t, 1, strings.Count(output.String(), "Date `2023-05-01' of bank dated does not match datePatternFrom `02.01.2006'"), output.String())
+}
+
+func TestPayeeAndPatternDateRanges(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Old gym": {
+				Name:      "Old gym",
+				Account:   "Expenses:Sport:Old",
+				PayeeRaw:  cfg.PayeePatterns{{Value: "^gym"}},
+				DateRange: cfg.DateRange{ValidTo: &cfg.Date{Time: time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC)}},
+			},
+			"Gym": {
+				Name:    "Gym",
+				Account: "Expenses:Sport",
+				PayeeRaw: cfg.PayeePatterns{
+					{Value: "^gym", DateRange: cfg.DateRange{ValidFrom: &cfg.Date{Time: time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)}}},
+				},
+			},
+		},
+	}
+
+	for date, expected := range map[string]string{
+		"31.12.2021": "Old gym",
+		"01.01.2022": "Gym",
+	} {
+		transaction := New(map[string]string{"payeeRaw": "GYM PRAHA", "dateRaw": date}, config, &cfg.Bank{DatePatternFrom: "02.01.2006"})
+		payee, exists := transaction.GetPayee()
+		assert.True(t, exists, date)
+		assert.Equal(t, expected, payee.Name, date)
+	}
+}
+
+func TestDirectionAccounts(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Alza": {
+				Name:            "Alza",
+				Account:         "Expenses:Electronics",
+				IncomingAccount: "Income:Refunds",
+				PayeeRaw:        cfg.PayeePatterns{{Value: "^alza"}},
+			},
+		},
+	}
+
+	purchase := New(map[string]string{"payeeRaw": "ALZA.CZ", "amountAccount": "-2000"}, config, &cfg.Bank{})
+	assert.Equal(t, "", purchase.GetDirectionAccountKey())
+	assert.Equal(t, "Expenses:Electronics", purchase.GetAccountTo())
+
+	refund := New(map[string]string{"payeeRaw": "ALZA.CZ", "amountAccount": "2000"}, config, &cfg.Bank{})
+	assert.Equal(t, "incomingAccount", refund.GetDirectionAccountKey())
+	assert.Equal(t, "Income:Refunds", refund.GetAccountTo())
+}
+
+func TestAccountRules_amount(t *testing.T) {
+	threshold := 10000.0
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Me": {
+				Name:     "Me",
+				Account:  "Assets:Other",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^me$"}},
+				Accounts: []cfg.AccountRule{
+					{Amount: cfg.AmountCondition{Sign: cfg.SignOutgoing, MinAmount: &threshold}, Account: "Assets:Savings"},
+				},
+			},
+		},
+	}
+
+	for amount, expected := range map[string]string{
+		"-10000": "Assets:Savings",
+		"-9999":  "Assets:Other",
+		"20000":  "Assets:Other",
+	} {
+		transaction := New(map[string]string{"payeeRaw": "me", "amountAccount": amount}, config, &cfg.Bank{})
+		assert.Equal(t, expected, transaction.GetAccountTo(), amount)
+	}
+}
+
+func TestNew(t *testing.T) {
+	transaction := New(map[string]string{
+		"payeeRaw":       "Tesco",
+		"amountAccount":  "-12,50",
+		"variableSymbol": "0042",
+		"flat":           "Main",
+	}, cfg.Config{}, &cfg.Bank{})
+
+	assert.Equal(t, "Tesco", transaction.PayeeRaw)
+	assert.Equal(t, -12.5, transaction.AmountAccount)
+	assert.Equal(t, "0042", transaction.VariableSymbol)
+	assert.Equal(t, "Main", transaction.Custom["flat"])
+}
+
+func TestRunConfigTest_pass(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Tesco": {
+				Name:     "Tesco",
+				Account:  "Expenses:Groceries",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^tesco"}},
+			},
+		},
+	}
+
+	amount := -250.0
+	differences, err := RunConfigTest(cfg.ConfigTest{
+		PayeeRaw:      "TESCO 1234 PRAHA",
+		Amount:        &amount,
+		ExpectPayee:   "Tesco",
+		ExpectAccount: "Expenses:Groceries",
+	}, config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(differences))
+}
+
+func TestRunConfigTest_fields_and_account_template(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Rent": {
+				Name:            "Rent",
+				AccountTemplate: "Expenses:Rent:{{ .Transaction.Custom.flat }}",
+				VariableSymbol:  cfg.PayeePatterns{{Value: "42"}},
+			},
+		},
+	}
+
+	differences, err := RunConfigTest(cfg.ConfigTest{
+		Fields:        map[string]string{"variableSymbol": "42", "flat": "Flat2"},
+		ExpectPayee:   "Rent",
+		ExpectAccount: "Expenses:Rent:Flat1",
+	}, config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []TestDifference{{"account", "Expenses:Rent:Flat1", "Expenses:Rent:Flat2"}}, differences)
+}
+
+func TestRunConfigTest_ignored_and_twin(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Tesco": {
+				Name:     "Tesco",
+				Account:  "Expenses:Groceries",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^tesco"}},
+			},
+		},
+		Banks: map[string]*cfg.Bank{
+			"mybank": {
+				Name: "mybank",
+				IgnoredTransactions: []cfg.IgnoredTransactions{
+					{Matchers: []cfg.Matcher{{PaymentType: "Block"}}},
+				},
+				TwinTransactions: []cfg.TwinTransaction{
+					{Type: "merge", Anchor: []cfg.Matcher{{Payee: "Tesco"}}},
+				},
+			},
+		},
+	}
+
+	ignored := false
+	differences, err := RunConfigTest(cfg.ConfigTest{
+		Bank:          "mybank",
+		PayeeRaw:      "Tesco",
+		Fields:        map[string]string{"paymentType": "Block"},
+		ExpectIgnored: &ignored,
+		ExpectTwin:    "none",
+	}, config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []TestDifference{
+		{"ignored", "false", "true"},
+		{"twin", "none", "merge"},
+	}, differences)
+}
+
+func TestRunConfigTest_date(t *testing.T) {
+	config := cfg.Config{
+		Payees: map[string]*cfg.Payee{
+			"Electricity": {
+				Name:     "Electricity",
+				Account:  "Expenses:Utilities",
+				PayeeRaw: cfg.PayeePatterns{{Value: "^pre"}},
+				Accounts: []cfg.AccountRule{
+					{
+						Account:   "Expenses:Flat1:Utilities",
+						DateRange: cfg.DateRange{ValidTo: &cfg.Date{Time: time.Date(2023, time.April, 30, 0, 0, 0, 0, time.UTC)}},
+					},
+					{
+						Account:   "Expenses:Flat2:Utilities",
+						DateRange: cfg.DateRange{ValidFrom: &cfg.Date{Time: time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC)}},
+					},
+				},
+			},
+		},
+		Banks: map[string]*cfg.Bank{
+			"mybank": {Name: "mybank", DatePatternFrom: "02.01.2006"},
+		},
+	}
+
+	// the default layout is used for tests without a bank
+	for _, bank := range []string{"", "mybank"} {
+		differences, err := RunConfigTest(cfg.ConfigTest{
+			Bank:          bank,
+			PayeeRaw:      "PRE 123",
+			Date:          &cfg.Date{Time: time.Date(2023, time.June, 15, 0, 0, 0, 0, time.UTC)},
+			ExpectAccount: "Expenses:Flat2:Utilities",
+		}, config)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(differences), bank)
+	}
+}
+
+func TestRunConfigTest_unknown_bank(t *testing.T) {
+	_, err := RunConfigTest(cfg.ConfigTest{Bank: "other", PayeeRaw: "Tesco"}, cfg.Config{})
+
+	assert.Equal(t, "bank `other' not found in config", err.Error())
+}